@@ -0,0 +1,75 @@
+//go:build linux
+
+package h1
+
+import (
+	"net"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// soIncomingCPU is SO_INCOMING_CPU, which the syscall package does not define.
+const soIncomingCPU = 0x31
+
+// cpuSet is a cpu_set_t of the sched_getaffinity and sched_setaffinity system calls.
+type cpuSet [1024 / 64]uint64
+
+func (set *cpuSet) affinity(trap uintptr) error {
+	_, _, errno := syscall.RawSyscall(trap, 0, unsafe.Sizeof(*set), uintptr(unsafe.Pointer(set)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// nth returns the n-th CPU of the set, counting from the first one again past the last one, or -1 if it is empty.
+func (set *cpuSet) nth(n int) int {
+	count := 0
+	for cpu := 0; cpu < len(set)*64; cpu++ {
+		if set[cpu/64]&(1<<(cpu%64)) != 0 {
+			count++
+		}
+	}
+	if count == 0 {
+		return -1
+	}
+	n %= count
+	for cpu := 0; cpu < len(set)*64; cpu++ {
+		if set[cpu/64]&(1<<(cpu%64)) != 0 {
+			if n == 0 {
+				return cpu
+			}
+			n--
+		}
+	}
+	return -1
+}
+
+// pinAcceptLoop runs the calling goroutine on the n-th CPU the process may use, and makes ln
+// prefer the connections whose packets the kernel handles on that CPU (SO_INCOMING_CPU).
+// The goroutine keeps its thread locked: the thread exits with it, and its affinity is not reused.
+// Pinning is best effort, the accept loop runs unpinned if it fails.
+func pinAcceptLoop(ln net.Listener, n int) {
+	var allowed cpuSet
+	if allowed.affinity(syscall.SYS_SCHED_GETAFFINITY) != nil {
+		return
+	}
+	cpu := allowed.nth(n)
+	if cpu < 0 {
+		return
+	}
+
+	runtime.LockOSThread()
+	var set cpuSet
+	set[cpu/64] = 1 << (cpu % 64)
+	set.affinity(syscall.SYS_SCHED_SETAFFINITY)
+
+	if sconn, ok := ln.(syscall.Conn); ok {
+		if raw, err := sconn.SyscallConn(); err == nil {
+			raw.Control(func(fd uintptr) {
+				syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soIncomingCPU, cpu)
+			})
+		}
+	}
+}
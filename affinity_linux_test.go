@@ -0,0 +1,83 @@
+//go:build linux
+
+package h1
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func Test_cpuSet_nth(t *testing.T) {
+	var set cpuSet
+	set[0] = 1<<2 | 1<<5
+	set[1] = 1 << 0
+
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{0, 2},
+		{1, 5},
+		{2, 64},
+		{3, 2},
+		{8, 64},
+	}
+	for _, tt := range tests {
+		if got := set.nth(tt.n); got != tt.want {
+			t.Errorf("nth(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+
+	var empty cpuSet
+	if got := empty.nth(0); got != -1 {
+		t.Errorf("nth(0) of an empty set = %d, want -1", got)
+	}
+}
+
+func Test_pinAcceptLoop(t *testing.T) {
+	var allowed cpuSet
+	if err := allowed.affinity(syscall.SYS_SCHED_GETAFFINITY); err != nil {
+		t.Skip(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	// The pinned goroutine exits with its thread
+	type result struct {
+		set cpuSet
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		pinAcceptLoop(ln, 1)
+		var r result
+		r.err = r.set.affinity(syscall.SYS_SCHED_GETAFFINITY)
+		done <- r
+	}()
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	want := allowed.nth(1)
+	if got := r.set.nth(0); got != want || r.set.nth(1) != want {
+		t.Errorf("accept loop runs on %v, want only CPU %d", r.set, want)
+	}
+
+	raw, err := ln.(syscall.Conn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cpu int
+	raw.Control(func(fd uintptr) {
+		cpu, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, soIncomingCPU)
+	})
+	if err != nil || cpu != want {
+		t.Errorf("SO_INCOMING_CPU = %d, %v, want %d", cpu, err, want)
+	}
+}
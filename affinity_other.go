@@ -0,0 +1,10 @@
+//go:build !linux
+
+package h1
+
+import (
+	"net"
+)
+
+// pinAcceptLoop does nothing where thread affinity and SO_INCOMING_CPU are not available.
+func pinAcceptLoop(ln net.Listener, n int) {}
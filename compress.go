@@ -0,0 +1,508 @@
+package h1
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+)
+
+// ContentCoding is a content-coding (RFC 9110 Section 8.4.1).
+type ContentCoding uint8
+
+const (
+	CodingIdentity ContentCoding = iota
+	CodingGzip
+	CodingDeflate
+)
+
+var codingName = [...][]byte{
+	CodingIdentity: []byte("identity"),
+	CodingGzip:     []byte("gzip"),
+	CodingDeflate:  []byte("deflate"),
+}
+
+func (c ContentCoding) String() string {
+	return string(codingName[c])
+}
+
+var AcceptEncodingHeader = []byte("Accept-Encoding")
+var ContentEncodingHeader = []byte("Content-Encoding")
+
+var xGzip = []byte("x-gzip")
+var wildcard = []byte("*")
+
+// ParseQValue parses a weight ("q=0.8") into thousandths. ok is false for an invalid weight.
+func ParseQValue(v []byte) (q int, ok bool) {
+	// qvalue = ( "0" [ "." 0*3DIGIT ] ) / ( "1" [ "." 0*3("0") ] )
+	if len(v) == 0 || len(v) > 5 || (v[0] != '0' && v[0] != '1') {
+		return 0, false
+	}
+	q = int(v[0]-'0') * 1000
+	if len(v) == 1 {
+		return q, true
+	}
+	if v[1] != '.' {
+		return 0, false
+	}
+	scale := 100
+	for _, c := range v[2:] {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		q += int(c-'0') * scale
+		scale /= 10
+	}
+	if q > 1000 {
+		return 0, false
+	}
+	return q, true
+}
+
+// parseWeightedToken splits a list element such as "gzip;q=0.5" into its token and weight.
+// Elements without a weight have a weight of 1000, elements with an invalid weight are ignored (weight 0).
+func parseWeightedToken(element []byte) (token []byte, q int) {
+	q = 1000
+	token = element
+	if semiIndex := indexByte(element, ';'); semiIndex != -1 {
+		token = element[:semiIndex]
+		params := element[semiIndex+1:]
+		for len(params) > 0 {
+			var param []byte
+			param, params = params, nil
+			if i := indexByte(param, ';'); i != -1 {
+				param, params = param[:i], param[i+1:]
+			}
+			param = trimLWS(param)
+			if len(param) >= 2 && (param[0]|0x20) == 'q' && param[1] == '=' {
+				var ok bool
+				q, ok = ParseQValue(param[2:])
+				if !ok {
+					q = 0
+				}
+			}
+		}
+	}
+	return trimLWS(token), q
+}
+
+// NegotiateEncoding picks the content-coding with the highest weight from an Accept-Encoding header value.
+// gzip is preferred over deflate when both have the same weight. CodingIdentity is returned if neither is acceptable.
+func NegotiateEncoding(acceptEncoding []byte) ContentCoding {
+	gzipQ, deflateQ, wildcardQ := -1, -1, -1
+
+	next := acceptEncoding
+	for len(next) > 0 {
+		var element []byte
+		element, next = next, nil
+		if commaIndex := indexByte(element, ','); commaIndex != -1 {
+			element, next = element[:commaIndex], element[commaIndex+1:]
+		}
+
+		token, q := parseWeightedToken(element)
+		switch {
+		case stricmp(token, codingName[CodingGzip]) || stricmp(token, xGzip):
+			gzipQ = q
+		case stricmp(token, codingName[CodingDeflate]):
+			deflateQ = q
+		case string(token) == string(wildcard):
+			wildcardQ = q
+		}
+	}
+
+	// "*" matches any coding not listed explicitly
+	if gzipQ < 0 {
+		gzipQ = wildcardQ
+	}
+	if deflateQ < 0 {
+		deflateQ = wildcardQ
+	}
+
+	switch {
+	case gzipQ > 0 && gzipQ >= deflateQ:
+		return CodingGzip
+	case deflateQ > 0:
+		return CodingDeflate
+	default:
+		return CodingIdentity
+	}
+}
+
+// AcceptedEncoding returns the preferred content-coding of the request's Accept-Encoding headers.
+func (r *Request) AcceptedEncoding() ContentCoding {
+	for i := range r.Headers {
+		if stricmp(r.Headers[i].Name, AcceptEncodingHeader) {
+			// Multiple Accept-Encoding headers are rare, use the first one with an acceptable coding
+			if c := NegotiateEncoding(r.Headers[i].RawValue); c != CodingIdentity {
+				return c
+			}
+		}
+	}
+	return CodingIdentity
+}
+
+// Content types that are already compressed (matched by prefix).
+var incompressibleTypes = [][]byte{
+	[]byte("image/"),
+	[]byte("video/"),
+	[]byte("audio/"),
+	[]byte("font/woff"),
+	[]byte("application/zip"),
+	[]byte("application/gzip"),
+	[]byte("application/x-gzip"),
+	[]byte("application/zstd"),
+	[]byte("application/x-bzip2"),
+	[]byte("application/x-xz"),
+	[]byte("application/x-7z-compressed"),
+	[]byte("application/x-rar-compressed"),
+	[]byte("application/vnd.rar"),
+	[]byte("application/wasm"),
+	[]byte("application/pdf"),
+}
+
+var compressibleImageTypes = [][]byte{
+	[]byte("image/svg+xml"),
+	[]byte("image/x-icon"),
+	[]byte("image/bmp"),
+}
+
+func hasPrefixFold(s, prefix []byte) bool {
+	return len(s) >= len(prefix) && stricmp(s[:len(prefix)], prefix)
+}
+
+// IsCompressible reports whether a response with the given Content-Type is worth compressing.
+func IsCompressible(contentType []byte) bool {
+	for _, t := range compressibleImageTypes {
+		if hasPrefixFold(contentType, t) {
+			return true
+		}
+	}
+	for _, t := range incompressibleTypes {
+		if hasPrefixFold(contentType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultCompressMinSize is the smallest Content-Length compressed when CompressWriter.MinSize is zero.
+const DefaultCompressMinSize = 1024
+
+// DefaultCompressMaxBufferSize is the compressed body size buffered for a Content-Length
+// when CompressWriter.MaxBufferSize is zero.
+const DefaultCompressMaxBufferSize = 64 << 10
+
+// compressor is implemented by *gzip.Writer and *zlib.Writer.
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// One pool per compression level (flate.HuffmanOnly to flate.BestCompression).
+var gzipWriterPools [flate.BestCompression - flate.HuffmanOnly + 1]sync.Pool
+var zlibWriterPools [flate.BestCompression - flate.HuffmanOnly + 1]sync.Pool
+
+func compressLevel(level int) int {
+	if level == 0 || level < flate.HuffmanOnly || level > flate.BestCompression {
+		return flate.DefaultCompression
+	}
+	return level
+}
+
+func getCompressor(coding ContentCoding, level int, w io.Writer) compressor {
+	level = compressLevel(level)
+	idx := level - flate.HuffmanOnly
+
+	switch coding {
+	case CodingGzip:
+		if zw, ok := gzipWriterPools[idx].Get().(*gzip.Writer); ok {
+			zw.Reset(w)
+			return zw
+		}
+		zw, _ := gzip.NewWriterLevel(w, level)
+		return zw
+	case CodingDeflate:
+		// "deflate" is the zlib format (RFC 9110, section 8.4.1.2)
+		if zw, ok := zlibWriterPools[idx].Get().(*zlib.Writer); ok {
+			zw.Reset(w)
+			return zw
+		}
+		zw, _ := zlib.NewWriterLevel(w, level)
+		return zw
+	}
+	return nil
+}
+
+func putCompressor(coding ContentCoding, level int, zw compressor) {
+	level = compressLevel(level)
+	idx := level - flate.HuffmanOnly
+
+	zw.Reset(io.Discard)
+	switch coding {
+	case CodingGzip:
+		gzipWriterPools[idx].Put(zw)
+	case CodingDeflate:
+		zlibWriterPools[idx].Put(zw)
+	}
+}
+
+// chunkWriter writes every Write as a chunk of the Response.
+type chunkWriter struct {
+	r *Response
+}
+
+func (w chunkWriter) Write(b []byte) (int, error) {
+	return w.r.WriteChunk(b)
+}
+
+// compressedWriter receives the output of the compressor of a CompressWriter.
+type compressedWriter struct {
+	cw *CompressWriter
+}
+
+func (w compressedWriter) Write(b []byte) (int, error) {
+	return w.cw.writeCompressed(b)
+}
+
+// CompressWriter writes a response body with the content-coding accepted by the client.
+//
+// Usage:
+//
+//	cw := h1.GetCompressWriter(resp, &reader.Request)
+//	resp.ContentLength = int64(len(body)) // or resp.Chunked = true for a streamed body
+//	cw.WriteHeader(200, contentType)
+//	// ... more headers (e.g. resp.WriteSetCookie)
+//	cw.EndHeader()
+//	cw.Write(body)
+//	cw.Close()
+//	h1.PutCompressWriter(cw)
+//
+// Compression is skipped for HEAD requests, 1xx/204/304 responses, already compressed content types
+// and bodies with a Content-Length below MinSize.
+// A compressed body with a Content-Length is buffered until Close, so the compressed length can be sent.
+// Once the compressed body outgrows MaxBufferSize, the header is sent without a Content-Length
+// and the body is streamed: chunked, or delimited by closing the connection for an HTTP/1.0 client.
+// A chunked body is compressed and framed as it is written.
+type CompressWriter struct {
+	Response *Response
+
+	// Coding is the negotiated content-coding. Set it to CodingIdentity to disable compression.
+	Coding ContentCoding
+	// Level is the compression level (flate.DefaultCompression if zero)
+	Level int
+	// MinSize is the smallest Content-Length to compress (DefaultCompressMinSize if zero)
+	MinSize int
+	// MaxBufferSize limits the compressed body buffered for a Content-Length (DefaultCompressMaxBufferSize if zero)
+	MaxBufferSize int
+
+	noBody     bool // HEAD request
+	http10     bool
+	compress   bool
+	buffered   bool
+	chunked    bool
+	headerDone bool
+
+	zw     compressor
+	dst    io.Writer
+	buffer *[]byte
+}
+
+var compressWriterPool = sync.Pool{
+	New: func() any {
+		return &CompressWriter{}
+	},
+}
+
+// GetCompressWriter returns a pooled CompressWriter for resp, negotiating the content-coding from req.
+func GetCompressWriter(resp *Response, req *Request) *CompressWriter {
+	cw := compressWriterPool.Get().(*CompressWriter)
+	cw.Response = resp
+	cw.Coding = req.AcceptedEncoding()
+	cw.noBody = req.Method == MethodHEAD
+	cw.http10 = string(req.Version) == string(http10)
+	return cw
+}
+
+// PutCompressWriter returns cw to the pool. Close must have been called.
+func PutCompressWriter(cw *CompressWriter) {
+	*cw = CompressWriter{}
+	compressWriterPool.Put(cw)
+}
+
+var varyAcceptEncodingHeader = []byte("Vary: Accept-Encoding\r\n")
+var contentEncodingGzipHeader = []byte("Content-Encoding: gzip\r\n")
+var contentEncodingDeflateHeader = []byte("Content-Encoding: deflate\r\n")
+var contentTypeHeader = []byte("Content-Type: ")
+
+// WriteHeader writes the status line, the standard headers, Content-Type and the content-coding headers.
+// The framing headers are written by EndHeader.
+func (cw *CompressWriter) WriteHeader(status int, contentType []byte) error {
+	r := cw.Response
+
+	minSize := cw.MinSize
+	if minSize == 0 {
+		minSize = DefaultCompressMinSize
+	}
+
+	hasBody := status >= 200 && status != 204 && status != 304 && !cw.noBody
+	compressible := hasBody && IsCompressible(contentType)
+	cw.compress = compressible && cw.Coding != CodingIdentity && (r.ContentLength < 0 || r.ContentLength >= int64(minSize))
+
+	err := r.WriteStatusLine(status)
+	if err != nil {
+		return err
+	}
+	err = r.writeDefaultHeaders()
+	if err != nil {
+		return err
+	}
+	if len(contentType) > 0 {
+		r.Write(contentTypeHeader)
+		r.Write(contentType)
+		r.Write(crlf)
+	}
+	if compressible {
+		r.Write(varyAcceptEncodingHeader)
+	}
+	if cw.compress {
+		switch cw.Coding {
+		case CodingGzip:
+			r.Write(contentEncodingGzipHeader)
+		case CodingDeflate:
+			r.Write(contentEncodingDeflateHeader)
+		}
+	}
+	return nil
+}
+
+// EndHeader writes the framing headers and ends the header section.
+// For a compressed body with a Content-Length, this is deferred to Close or until MaxBufferSize is exceeded.
+func (cw *CompressWriter) EndHeader() error {
+	r := cw.Response
+	cw.headerDone = true
+
+	if cw.compress && r.ContentLength >= 0 {
+		// The compressed length is not known yet, buffer the body
+		cw.buffered = true
+		cw.buffer = GetBuffer()
+		*cw.buffer = (*cw.buffer)[:0]
+		cw.zw = getCompressor(cw.Coding, cw.Level, compressedWriter{cw})
+		return nil
+	}
+
+	err := cw.endFraming()
+	if err != nil {
+		return err
+	}
+	if cw.compress {
+		cw.zw = getCompressor(cw.Coding, cw.Level, cw.dst)
+	}
+	return nil
+}
+
+// endFraming writes the framing headers of the Response, ends the header section and sets the body destination.
+func (cw *CompressWriter) endFraming() error {
+	r := cw.Response
+	err := r.writeFraming()
+	if err != nil {
+		return err
+	}
+	err = r.EndHeader()
+	if err != nil {
+		return err
+	}
+
+	cw.chunked = r.ContentLength < 0 && r.Chunked
+	cw.dst = r
+	if cw.chunked {
+		cw.dst = chunkWriter{r}
+	}
+	return nil
+}
+
+// writeCompressed buffers the compressed body while it fits in MaxBufferSize.
+// Past it, the buffered part is sent and the rest of the body is streamed.
+func (cw *CompressWriter) writeCompressed(b []byte) (int, error) {
+	if cw.buffered {
+		maxSize := cw.MaxBufferSize
+		if maxSize == 0 {
+			maxSize = DefaultCompressMaxBufferSize
+		}
+		if len(*cw.buffer)+len(b) <= maxSize {
+			*cw.buffer = append(*cw.buffer, b...)
+			return len(b), nil
+		}
+
+		r := cw.Response
+		r.ContentLength = -1
+		r.Chunked = !cw.http10
+		err := cw.endFraming()
+		if err == nil && len(*cw.buffer) > 0 {
+			_, err = cw.dst.Write(*cw.buffer)
+		}
+		PutBuffer(cw.buffer)
+		cw.buffer = nil
+		cw.buffered = false
+		if err != nil {
+			return 0, err
+		}
+	}
+	return cw.dst.Write(b)
+}
+
+// Write writes (and compresses) a part of the body.
+func (cw *CompressWriter) Write(b []byte) (int, error) {
+	if !cw.headerDone {
+		err := cw.EndHeader()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if cw.zw != nil {
+		return cw.zw.Write(b)
+	}
+	return cw.dst.Write(b)
+}
+
+// Close finishes the body. It does not flush the Response.
+func (cw *CompressWriter) Close() error {
+	if !cw.headerDone {
+		err := cw.EndHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if cw.zw != nil {
+		err = cw.zw.Close()
+		putCompressor(cw.Coding, cw.Level, cw.zw)
+		cw.zw = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	r := cw.Response
+	if cw.buffered {
+		r.ContentLength = int64(len(*cw.buffer))
+		err = r.writeFraming()
+		if err == nil {
+			err = r.EndHeader()
+		}
+		if err == nil {
+			_, err = r.Write(*cw.buffer)
+		}
+		PutBuffer(cw.buffer)
+		cw.buffer = nil
+		cw.buffered = false
+		return err
+	}
+
+	if cw.chunked {
+		cw.chunked = false
+		return r.WriteLastChunk()
+	}
+	return nil
+}
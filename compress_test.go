@@ -0,0 +1,267 @@
+package h1
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_NegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   ContentCoding
+	}{
+		{"", CodingIdentity},
+		{"gzip", CodingGzip},
+		{"deflate", CodingDeflate},
+		{"gzip, deflate, br", CodingGzip},
+		{"deflate, gzip", CodingGzip},
+		{"gzip;q=0.5, deflate", CodingDeflate},
+		{"gzip;q=0, deflate;q=0", CodingIdentity},
+		{"GZIP; Q=0.8", CodingGzip},
+		{"x-gzip", CodingGzip},
+		{"br, identity", CodingIdentity},
+		{"*", CodingGzip},
+		{"*;q=0.1, gzip;q=0", CodingDeflate},
+		{"gzip;q=1.5", CodingIdentity},
+		{"gzip;q=abc, deflate;q=0.001", CodingDeflate},
+		{"br;q=1.0, gzip;q=0.8, *;q=0.1", CodingGzip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := NegotiateEncoding([]byte(tt.accept)); got != tt.want {
+				t.Errorf("NegotiateEncoding(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseQValue(t *testing.T) {
+	tests := []struct {
+		v      string
+		want   int
+		wantOK bool
+	}{
+		{"1", 1000, true},
+		{"0", 0, true},
+		{"0.5", 500, true},
+		{"0.123", 123, true},
+		{"1.000", 1000, true},
+		{"1.001", 0, false},
+		{"0.1234", 0, false},
+		{"2", 0, false},
+		{"", 0, false},
+		{"0.a", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseQValue([]byte(tt.v))
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseQValue(%q) = %d, %v, want %d, %v", tt.v, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func compressTestResponse(t *testing.T, request string, contentLength int, chunked bool, status int, contentType string, body []byte) *http.Response {
+	t.Helper()
+	r := newTestRequestReader(request)
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	resp := GetResponse(&buffer)
+	defer PutResponse(resp)
+
+	cw := GetCompressWriter(resp, &r.Request)
+	defer PutCompressWriter(cw)
+
+	resp.ContentLength = int64(contentLength)
+	resp.Chunked = chunked
+	cw.WriteHeader(status, []byte(contentType))
+	resp.WriteString("X-Custom: 1\r\n")
+	cw.EndHeader()
+	// Write in pieces to exercise streaming
+	for len(body) > 0 {
+		n := 1000
+		if n > len(body) {
+			n = len(body)
+		}
+		if _, err := cw.Write(body[:n]); err != nil {
+			t.Fatal(err)
+		}
+		body = body[n:]
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(&buffer), nil)
+	if err != nil {
+		t.Fatalf("ReadResponse() error = %v\n%s", err, buffer.Bytes())
+	}
+	return res
+}
+
+func Test_CompressWriter(t *testing.T) {
+	body := []byte(strings.Repeat("Hello, World! ", 2000))
+	small := []byte("tiny")
+
+	tests := []struct {
+		name          string
+		acceptEnc     string
+		contentLength int
+		chunked       bool
+		status        int
+		contentType   string
+		body          []byte
+		wantEncoding  string
+		wantVary      bool
+		wantChunked   bool
+	}{
+		{"gzip content-length", "gzip", len(body), false, 200, "text/plain", body, "gzip", true, false},
+		{"deflate content-length", "deflate", len(body), false, 200, "text/html", body, "deflate", true, false},
+		{"gzip chunked", "gzip", -1, true, 200, "application/json", body, "gzip", true, true},
+		{"identity chunked", "", -1, true, 200, "application/json", body, "", true, true},
+		{"identity content-length", "br", len(body), false, 200, "text/plain", body, "", true, false},
+		{"small body", "gzip", len(small), false, 200, "text/plain", small, "", true, false},
+		{"already compressed", "gzip", len(body), false, 200, "image/png", body, "", false, false},
+		{"svg", "gzip", len(body), false, 200, "image/svg+xml", body, "gzip", true, false},
+		{"no content", "gzip", 0, false, 204, "text/plain", nil, "", false, false},
+		{"not modified", "gzip", -1, false, 304, "text/plain", nil, "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := "GET / HTTP/1.1\r\nHost: localhost\r\nAccept-Encoding: " + tt.acceptEnc + "\r\n\r\n"
+			res := compressTestResponse(t, request, tt.contentLength, tt.chunked, tt.status, tt.contentType, tt.body)
+			defer res.Body.Close()
+
+			if got := res.Header.Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := res.Header.Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Errorf("Vary = %q, want Accept-Encoding: %v", res.Header.Get("Vary"), tt.wantVary)
+			}
+			if got := len(res.TransferEncoding) > 0; got != tt.wantChunked {
+				t.Errorf("chunked = %v, want %v", got, tt.wantChunked)
+			}
+			if res.Header.Get("X-Custom") != "1" {
+				t.Error("custom header missing")
+			}
+
+			var reader io.Reader = res.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				zr, err := gzip.NewReader(res.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				reader = zr
+			case "deflate":
+				zr, err := zlib.NewReader(res.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				reader = zr
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.body) {
+				t.Errorf("body = %d bytes, want %d bytes", len(got), len(tt.body))
+			}
+			if tt.wantEncoding != "" && !tt.wantChunked && res.ContentLength >= int64(len(tt.body)) {
+				t.Errorf("Content-Length = %d, want compressed length", res.ContentLength)
+			}
+		})
+	}
+}
+
+func Test_CompressWriter_BufferOverflow(t *testing.T) {
+	// Random bytes do not compress, the compressed body outgrows the buffer
+	body := make([]byte, 4*DefaultCompressMaxBufferSize)
+	rand.New(rand.NewSource(1)).Read(body)
+
+	tests := []struct {
+		name        string
+		version     string
+		wantChunked bool
+	}{
+		{"HTTP/1.1", "HTTP/1.1", true},
+		{"HTTP/1.0", "HTTP/1.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := "GET / " + tt.version + "\r\nAccept-Encoding: gzip\r\n\r\n"
+			res := compressTestResponse(t, request, len(body), false, 200, "application/octet-stream", body)
+			defer res.Body.Close()
+
+			if res.ContentLength != -1 {
+				t.Errorf("Content-Length = %d, want none", res.ContentLength)
+			}
+			if got := len(res.TransferEncoding) > 0; got != tt.wantChunked {
+				t.Errorf("chunked = %v, want %v", got, tt.wantChunked)
+			}
+			zr, err := gzip.NewReader(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, body) {
+				t.Errorf("body = %d bytes, want %d bytes", len(got), len(body))
+			}
+		})
+	}
+}
+
+func Test_CompressWriter_HEAD(t *testing.T) {
+	r := newTestRequestReader("HEAD / HTTP/1.1\r\nAccept-Encoding: gzip\r\n\r\n")
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	resp := GetResponse(io.Discard)
+	defer PutResponse(resp)
+	cw := GetCompressWriter(resp, &r.Request)
+	defer PutCompressWriter(cw)
+
+	resp.ContentLength = 4096
+	cw.WriteHeader(200, []byte("text/plain"))
+	if cw.compress {
+		t.Error("HEAD response should not be compressed")
+	}
+}
+
+func Benchmark_CompressWriter(b *testing.B) {
+	body := []byte(strings.Repeat("Hello, World! ", 200))
+	r := newTestRequestReader("GET / HTTP/1.1\r\nAccept-Encoding: gzip, deflate\r\n\r\n")
+	r.Next()
+	contentType := []byte("text/plain")
+	b.ReportAllocs()
+	b.RunParallel(func(p *testing.PB) {
+		resp := GetResponse(io.Discard)
+		for p.Next() {
+			cw := GetCompressWriter(resp, &r.Request)
+			resp.ContentLength = int64(len(body))
+			cw.WriteHeader(200, contentType)
+			cw.EndHeader()
+			cw.Write(body)
+			cw.Close()
+			PutCompressWriter(cw)
+			resp.Flush()
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package h1
+
+import (
+	"time"
+)
+
+var IfMatchHeader = []byte("If-Match")
+var IfNoneMatchHeader = []byte("If-None-Match")
+var IfModifiedSinceHeader = []byte("If-Modified-Since")
+var IfUnmodifiedSinceHeader = []byte("If-Unmodified-Since")
+
+// Precondition is the result of evaluating the conditional headers of a request.
+type Precondition uint8
+
+const (
+	PreconditionProceed     Precondition = iota // perform the method
+	PreconditionNotModified                     // respond with 304 Not Modified
+	PreconditionFailed                          // respond with 412 Precondition Failed
+)
+
+// EvaluatePreconditions evaluates the conditional headers of the request for the selected representation,
+// in the order of RFC 9110 Section 13.2.2. An empty etag means the representation has no entity-tag,
+// a nil etag means the resource does not exist. A zero modtime means there is no modification date.
+// If-Range is evaluated by Ranges.
+func (r *Request) EvaluatePreconditions(etag []byte, modtime time.Time) Precondition {
+	// Step 1 and 2
+	if present, match := r.matchETagHeaders(IfMatchHeader, etag, false); present {
+		if !match {
+			return PreconditionFailed
+		}
+	} else if t, ok := r.dateHeader(IfUnmodifiedSinceHeader); ok && !modtime.IsZero() {
+		if modtime.Unix() > t.Unix() {
+			return PreconditionFailed
+		}
+	}
+
+	// Step 3 and 4
+	safe := r.Method == MethodGET || r.Method == MethodHEAD
+	if present, match := r.matchETagHeaders(IfNoneMatchHeader, etag, true); present {
+		if match {
+			if safe {
+				return PreconditionNotModified
+			}
+			return PreconditionFailed
+		}
+	} else if t, ok := r.dateHeader(IfModifiedSinceHeader); ok && safe && !modtime.IsZero() {
+		if modtime.Unix() <= t.Unix() {
+			return PreconditionNotModified
+		}
+	}
+
+	return PreconditionProceed
+}
+
+// matchETagHeaders reports whether the request has a name header and whether any of its entity-tags
+// (across all header lines) matches etag. "*" matches any existing representation.
+func (r *Request) matchETagHeaders(name, etag []byte, weak bool) (present, match bool) {
+	for i := range r.Headers {
+		if !stricmp(r.Headers[i].Name, name) {
+			continue
+		}
+		present = true
+		if matchETagList(r.Headers[i].RawValue, etag, weak) {
+			return true, true
+		}
+	}
+	return present, false
+}
+
+// matchETagList reports whether an entity-tag list contains etag.
+// The weak comparison ignores the W/ prefix, the strong comparison never matches a weak entity-tag.
+func matchETagList(list, etag []byte, weak bool) bool {
+	if etag == nil {
+		return false
+	}
+	if string(trimLWS(list)) == "*" {
+		return true
+	}
+	if len(etag) == 0 || (!weak && isWeakETag(etag)) {
+		return false
+	}
+	if weak {
+		etag = trimWeakPrefix(etag)
+	}
+
+	for len(list) > 0 {
+		var element []byte
+		element, list = list, nil
+		if commaIndex := indexByte(element, ','); commaIndex != -1 {
+			element, list = element[:commaIndex], element[commaIndex+1:]
+		}
+		element = trimLWS(element)
+		if weak {
+			element = trimWeakPrefix(element)
+		}
+		if string(element) == string(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWeakETag(etag []byte) bool {
+	return len(etag) > 2 && etag[0] == 'W' && etag[1] == '/'
+}
+
+func trimWeakPrefix(etag []byte) []byte {
+	if isWeakETag(etag) {
+		return etag[2:]
+	}
+	return etag
+}
+
+// dateHeader parses the first name header as an HTTP date. Invalid dates are ignored.
+func (r *Request) dateHeader(name []byte) (time.Time, bool) {
+	h, ok := r.GetHeader(name)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := ParseHTTPDate(trimLWS(h.RawValue))
+	return t, err == nil
+}
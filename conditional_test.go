@@ -0,0 +1,89 @@
+package h1
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Request_EvaluatePreconditions(t *testing.T) {
+	modtime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := modtime.Format(http.TimeFormat)
+	before := modtime.Add(-time.Hour).Format(http.TimeFormat)
+	after := modtime.Add(time.Hour).Format(http.TimeFormat)
+	strong := []byte(`"v1"`)
+	weak := []byte(`W/"v1"`)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers string
+		etag    []byte
+		modtime time.Time
+		want    Precondition
+	}{
+		{"None", "GET", "", strong, modtime, PreconditionProceed},
+
+		{"If-Match", "PUT", "If-Match: \"v1\"\r\n", strong, modtime, PreconditionProceed},
+		{"If-Match List", "PUT", "If-Match: \"v0\", \"v1\"\r\n", strong, modtime, PreconditionProceed},
+		{"If-Match Lines", "PUT", "If-Match: \"v0\"\r\nIf-Match: \"v1\"\r\n", strong, modtime, PreconditionProceed},
+		{"If-Match Mismatch", "PUT", "If-Match: \"v2\"\r\n", strong, modtime, PreconditionFailed},
+		{"If-Match Weak", "PUT", "If-Match: W/\"v1\"\r\n", strong, modtime, PreconditionFailed},
+		{"If-Match Weak Current", "PUT", "If-Match: \"v1\"\r\n", weak, modtime, PreconditionFailed},
+		{"If-Match Star", "PUT", "If-Match: *\r\n", strong, modtime, PreconditionProceed},
+		{"If-Match Star Missing", "PUT", "If-Match: *\r\n", nil, time.Time{}, PreconditionFailed},
+		{"If-Match No ETag", "PUT", "If-Match: \"v1\"\r\n", []byte{}, modtime, PreconditionFailed},
+
+		{"If-Unmodified-Since", "PUT", "If-Unmodified-Since: " + now + "\r\n", strong, modtime, PreconditionProceed},
+		{"If-Unmodified-Since Later", "PUT", "If-Unmodified-Since: " + after + "\r\n", strong, modtime, PreconditionProceed},
+		{"If-Unmodified-Since Earlier", "PUT", "If-Unmodified-Since: " + before + "\r\n", strong, modtime, PreconditionFailed},
+		{"If-Unmodified-Since Invalid", "PUT", "If-Unmodified-Since: yesterday\r\n", strong, modtime, PreconditionProceed},
+		{"If-Unmodified-Since No Date", "PUT", "If-Unmodified-Since: " + before + "\r\n", strong, time.Time{}, PreconditionProceed},
+		{"If-Match Over If-Unmodified-Since", "PUT", "If-Match: \"v1\"\r\nIf-Unmodified-Since: " + before + "\r\n", strong, modtime, PreconditionProceed},
+
+		{"If-None-Match GET", "GET", "If-None-Match: \"v1\"\r\n", strong, modtime, PreconditionNotModified},
+		{"If-None-Match HEAD", "HEAD", "If-None-Match: \"v1\"\r\n", strong, modtime, PreconditionNotModified},
+		{"If-None-Match Weak", "GET", "If-None-Match: W/\"v1\"\r\n", strong, modtime, PreconditionNotModified},
+		{"If-None-Match Weak Current", "GET", "If-None-Match: \"v1\"\r\n", weak, modtime, PreconditionNotModified},
+		{"If-None-Match Mismatch", "GET", "If-None-Match: \"v0\", \"v2\"\r\n", strong, modtime, PreconditionProceed},
+		{"If-None-Match PUT", "PUT", "If-None-Match: \"v1\"\r\n", strong, modtime, PreconditionFailed},
+		{"If-None-Match Star PUT", "PUT", "If-None-Match: *\r\n", strong, modtime, PreconditionFailed},
+		{"If-None-Match Star Create", "PUT", "If-None-Match: *\r\n", nil, time.Time{}, PreconditionProceed},
+
+		{"If-Modified-Since", "GET", "If-Modified-Since: " + now + "\r\n", strong, modtime, PreconditionNotModified},
+		{"If-Modified-Since Later", "GET", "If-Modified-Since: " + after + "\r\n", strong, modtime, PreconditionNotModified},
+		{"If-Modified-Since Earlier", "GET", "If-Modified-Since: " + before + "\r\n", strong, modtime, PreconditionProceed},
+		{"If-Modified-Since POST", "POST", "If-Modified-Since: " + now + "\r\n", strong, modtime, PreconditionProceed},
+		{"If-None-Match Over If-Modified-Since", "GET", "If-None-Match: \"v0\"\r\nIf-Modified-Since: " + now + "\r\n", strong, modtime, PreconditionProceed},
+
+		{"If-Match Before If-None-Match", "GET", "If-Match: \"v2\"\r\nIf-None-Match: \"v1\"\r\n", strong, modtime, PreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newTestRequestReader(tt.method + " / HTTP/1.1\r\nContent-Length: 0\r\n" + tt.headers + "\r\n")
+			if _, err := reader.Next(); err != nil {
+				t.Fatal(err)
+			}
+			if got := reader.Request.EvaluatePreconditions(tt.etag, tt.modtime); got != tt.want {
+				t.Errorf("EvaluatePreconditions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Request_EvaluatePreconditions_Allocs(t *testing.T) {
+	reader := newTestRequestReader("GET / HTTP/1.1\r\nIf-Match: \"a\", \"v1\"\r\nIf-None-Match: \"b\"\r\nIf-Modified-Since: Sun, 02 Jan 2022 03:04:05 GMT\r\n\r\n")
+	if _, err := reader.Next(); err != nil {
+		t.Fatal(err)
+	}
+	etag := []byte(`"v1"`)
+	modtime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		reader.Request.EvaluatePreconditions(etag, modtime)
+	})
+	if allocs != 0 {
+		t.Errorf("EvaluatePreconditions() allocates %v times", allocs)
+	}
+}
@@ -0,0 +1,299 @@
+package h1
+
+import (
+	"errors"
+	"time"
+)
+
+var CookieHeader = []byte("Cookie")
+
+// CookiePair is a single name=value pair from a Cookie request header.
+// Name and Value point into the request buffer.
+type CookiePair struct {
+	Name  []byte
+	Value []byte
+}
+
+// CookieIterator iterates over the cookie pairs of every Cookie header of a request without allocating.
+type CookieIterator struct {
+	headers []Header
+	index   int
+
+	next []byte
+}
+
+// Next returns the next cookie pair. ok is false when there are no more cookies.
+func (it *CookieIterator) Next() (name, value []byte, ok bool) {
+	for {
+		for len(it.next) == 0 {
+			// Find the next Cookie header
+			if it.index >= len(it.headers) {
+				return nil, nil, false
+			}
+			h := &it.headers[it.index]
+			it.index++
+			if stricmp(h.Name, CookieHeader) {
+				it.next = h.RawValue
+			}
+		}
+
+		var pair []byte
+		semiIndex := indexByte(it.next, ';')
+		if semiIndex != -1 {
+			pair = it.next[:semiIndex]
+			it.next = it.next[semiIndex+1:]
+		} else {
+			pair = it.next
+			it.next = nil
+		}
+
+		name, value = ParseCookiePair(pair)
+		if len(name) > 0 {
+			return name, value, true
+		}
+	}
+}
+
+// ParseCookiePair splits a single cookie-pair into its name and value.
+// Surrounding whitespace and the optional double quotes around the value are removed.
+// A pair without '=' is returned as a name with an empty value.
+func ParseCookiePair(pair []byte) (name, value []byte) {
+	name = pair
+	value = pair[len(pair):]
+	eqIndex := indexByte(pair, '=')
+	if eqIndex != -1 {
+		name = pair[:eqIndex]
+		value = pair[eqIndex+1:]
+	}
+
+	name = trimLWS(name)
+	value = trimLWS(value)
+
+	// cookie-value = *cookie-octet / ( DQUOTE *cookie-octet DQUOTE )
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return name, value
+}
+
+func trimLWS(b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	i := skipLWS(b, 0, len(b))
+	j := skipLWSReverse(b, i, len(b)-1)
+	return b[i : j+1]
+}
+
+// IterCookies returns an iterator over the cookies of the request.
+func (r *Request) IterCookies() CookieIterator {
+	return CookieIterator{
+		headers: r.Headers,
+	}
+}
+
+func (r *Request) parseCookies() {
+	it := r.IterCookies()
+	for name, value, ok := it.Next(); ok; name, value, ok = it.Next() {
+		r.cookies = append(r.cookies, CookiePair{
+			Name:  name,
+			Value: value,
+		})
+	}
+}
+
+// Cookies returns all cookies sent with the request, in order.
+// The result is cached until the request is reset.
+func (r *Request) Cookies() []CookiePair {
+	if !r.isCookieParsed {
+		r.parseCookies()
+		r.isCookieParsed = true
+	}
+
+	return r.cookies
+}
+
+// Cookie returns the value of the first cookie named name.
+func (r *Request) Cookie(name []byte) ([]byte, error) {
+	for _, c := range r.Cookies() {
+		if string(c.Name) == string(name) {
+			return c.Value, nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+type SameSite uint8
+
+const (
+	SameSiteDefault SameSite = iota // SameSite attribute is not written
+	SameSiteLax
+	SameSiteStrict
+	SameSiteNone
+)
+
+// Cookie is a cookie sent to the client with a Set-Cookie response header (RFC 6265).
+type Cookie struct {
+	Name  string
+	Value string
+
+	Path    string
+	Domain  string
+	Expires time.Time // Zero value means no Expires attribute
+
+	// MaxAge = 0 means no Max-Age attribute.
+	// MaxAge < 0 means delete the cookie now (Max-Age=0).
+	// MaxAge > 0 means Max-Age attribute present and given in seconds.
+	MaxAge int
+
+	Secure      bool
+	HttpOnly    bool
+	SameSite    SameSite
+	Partitioned bool // Requires Secure
+}
+
+var ErrInvalidCookieName = errors.New("invalid cookie name")
+var ErrInvalidCookieValue = errors.New("invalid cookie value")
+var ErrInvalidCookieAttribute = errors.New("invalid cookie attribute")
+
+// cookieValueTable reports whether a byte is a cookie-octet.
+var cookieValueTable [256]bool
+
+var _ = func() int {
+	for c := 0x21; c <= 0x7e; c++ {
+		cookieValueTable[c] = true
+	}
+	cookieValueTable['"'] = false
+	cookieValueTable[','] = false
+	cookieValueTable[';'] = false
+	cookieValueTable['\\'] = false
+	return 0
+}()
+
+func validCookieValue(v string) bool {
+	// The value may be wrapped in double quotes
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	for i := 0; i < len(v); i++ {
+		if !cookieValueTable[v[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+// validCookieAttribute reports whether v is a valid Path or Domain attribute value (any CHAR except CTLs or ";").
+func validCookieAttribute(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if v[i] < 0x20 || v[i] >= 0x7f || v[i] == ';' {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate reports whether the cookie can be serialised.
+func (c *Cookie) Validate() error {
+	if len(c.Name) == 0 || indexNonToken(stringToBytes(c.Name)) != len(c.Name) {
+		return ErrInvalidCookieName
+	}
+	if !validCookieValue(c.Value) {
+		return ErrInvalidCookieValue
+	}
+	if !validCookieAttribute(c.Path) || !validCookieAttribute(c.Domain) {
+		return ErrInvalidCookieAttribute
+	}
+	if c.Partitioned && !c.Secure {
+		return ErrInvalidCookieAttribute
+	}
+	return nil
+}
+
+var setCookieHeader = []byte("Set-Cookie: ")
+
+// WriteSetCookie writes a Set-Cookie header line for c.
+// Nothing is written if the cookie is invalid.
+func (r *Response) WriteSetCookie(c *Cookie) error {
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+
+	w := cookieWriter{r: r}
+	w.write(setCookieHeader)
+	w.writeString(c.Name)
+	w.writeString("=")
+	w.writeString(c.Value)
+
+	if len(c.Path) > 0 {
+		w.writeString("; Path=")
+		w.writeString(c.Path)
+	}
+	if len(c.Domain) > 0 {
+		w.writeString("; Domain=")
+		w.writeString(c.Domain)
+	}
+	if !c.Expires.IsZero() {
+		w.writeString("; Expires=")
+		w.write(r.cookieExpires(c.Expires))
+	}
+	if c.MaxAge > 0 {
+		w.writeString("; Max-Age=")
+		if w.err == nil {
+			_, w.err = r.WriteInt(c.MaxAge)
+		}
+	} else if c.MaxAge < 0 {
+		w.writeString("; Max-Age=0")
+	}
+	if c.HttpOnly {
+		w.writeString("; HttpOnly")
+	}
+	if c.Secure {
+		w.writeString("; Secure")
+	}
+	switch c.SameSite {
+	case SameSiteLax:
+		w.writeString("; SameSite=Lax")
+	case SameSiteStrict:
+		w.writeString("; SameSite=Strict")
+	case SameSiteNone:
+		w.writeString("; SameSite=None")
+	}
+	if c.Partitioned {
+		w.writeString("; Partitioned")
+	}
+
+	w.write(crlf)
+	return w.err
+}
+
+// cookieWriter writes to a Response until the first error.
+type cookieWriter struct {
+	r   *Response
+	err error
+}
+
+func (w *cookieWriter) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.r.Write(b)
+	}
+}
+
+func (w *cookieWriter) writeString(s string) {
+	if w.err == nil {
+		_, w.err = w.r.WriteString(s)
+	}
+}
+
+// cookieExpires returns t formatted as an IMF-fixdate.
+// Like FastDateServer, the formatted date is kept per second, so cookies sharing an expiry are only formatted once.
+func (r *Response) cookieExpires(t time.Time) []byte {
+	unix := t.Unix()
+	if unix != r.expiresUnix || len(r.expiresBuf) == 0 {
+		r.expiresUnix = unix
+		r.expiresBuf = AppendHTTPDate(r.expiresBuf[:0], t)
+	}
+	return r.expiresBuf
+}
@@ -0,0 +1,180 @@
+package h1
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Request_Cookies(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		want    [][2]string
+	}{
+		{"no cookies", "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n", nil},
+		{"single", "GET / HTTP/1.1\r\nCookie: a=1\r\n\r\n", [][2]string{{"a", "1"}}},
+		{"multiple", "GET / HTTP/1.1\r\nCookie: a=1; b=2;c=3\r\n\r\n", [][2]string{{"a", "1"}, {"b", "2"}, {"c", "3"}}},
+		{"quoted", "GET / HTTP/1.1\r\nCookie: a=\"hello world\"; b=\"\"\r\n\r\n", [][2]string{{"a", "hello world"}, {"b", ""}}},
+		{"whitespace", "GET / HTTP/1.1\r\nCookie:  a = 1 ;\tb=2 ; \r\n\r\n", [][2]string{{"a", "1"}, {"b", "2"}}},
+		{"empty value", "GET / HTTP/1.1\r\nCookie: a=; b\r\n\r\n", [][2]string{{"a", ""}, {"b", ""}}},
+		{"empty pairs", "GET / HTTP/1.1\r\nCookie: ;; a=1;;=2\r\n\r\n", [][2]string{{"a", "1"}}},
+		{"equals in value", "GET / HTTP/1.1\r\nCookie: token=abc==\r\n\r\n", [][2]string{{"token", "abc=="}}},
+		{"multiple headers", "GET / HTTP/1.1\r\nCookie: a=1\r\nHost: localhost\r\ncookie: b=2; c=3\r\n\r\n", [][2]string{{"a", "1"}, {"b", "2"}, {"c", "3"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseRequestForTest([]byte(tt.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := r.Cookies()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Cookies() got %d cookies, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if string(got[i].Name) != tt.want[i][0] || string(got[i].Value) != tt.want[i][1] {
+					t.Errorf("cookie %d = %q=%q, want %q=%q", i, got[i].Name, got[i].Value, tt.want[i][0], tt.want[i][1])
+				}
+			}
+		})
+	}
+}
+
+func Test_Request_Cookie(t *testing.T) {
+	r, err := parseRequestForTest([]byte("GET / HTTP/1.1\r\nCookie: session=abc; theme=dark\r\nCookie: session=def\r\n\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := r.Cookie([]byte("session"))
+	if err != nil || !bytes.Equal(v, []byte("abc")) {
+		t.Errorf("Cookie(session) = %q, %v, want %q", v, err, "abc")
+	}
+	v, err = r.Cookie([]byte("theme"))
+	if err != nil || !bytes.Equal(v, []byte("dark")) {
+		t.Errorf("Cookie(theme) = %q, %v, want %q", v, err, "dark")
+	}
+	if _, err = r.Cookie([]byte("Session")); err != ErrKeyNotFound {
+		t.Errorf("Cookie(Session) error = %v, want %v", err, ErrKeyNotFound)
+	}
+
+	r.Reset()
+	if len(r.Cookies()) != 0 {
+		t.Errorf("Cookies() after Reset() = %d cookies, want 0", len(r.Cookies()))
+	}
+}
+
+func Benchmark_Request_Cookie(b *testing.B) {
+	b.ReportAllocs()
+	data := []byte("GET / HTTP/1.1\r\nHost: localhost\r\nCookie: _ga=GA1.1.123456789.1234567890; theme=dark; session=\"0123456789abcdef\"\r\n\r\n")
+	name := []byte("session")
+	b.RunParallel(func(p *testing.PB) {
+		request := &Request{}
+		for p.Next() {
+			next, _ := ParseRequestLine(request, data)
+			ParseHeaders(request, next)
+			request.Cookie(name)
+			request.Reset()
+		}
+	})
+}
+
+func Test_Response_WriteSetCookie(t *testing.T) {
+	expires := time.Date(2030, time.March, 4, 5, 6, 7, 0, time.FixedZone("KST", 9*60*60))
+	tests := []struct {
+		name    string
+		cookie  Cookie
+		want    string
+		wantErr error
+	}{
+		{"simple", Cookie{Name: "a", Value: "1"}, "Set-Cookie: a=1\r\n", nil},
+		{"empty value", Cookie{Name: "a"}, "Set-Cookie: a=\r\n", nil},
+		{"quoted value", Cookie{Name: "a", Value: "\"abc\""}, "Set-Cookie: a=\"abc\"\r\n", nil},
+		{
+			"all attributes",
+			Cookie{
+				Name: "session", Value: "abc123", Path: "/", Domain: "example.com",
+				Expires: expires, MaxAge: 3600, Secure: true, HttpOnly: true,
+				SameSite: SameSiteLax, Partitioned: true,
+			},
+			"Set-Cookie: session=abc123; Path=/; Domain=example.com; Expires=Sun, 03 Mar 2030 20:06:07 GMT; Max-Age=3600; HttpOnly; Secure; SameSite=Lax; Partitioned\r\n",
+			nil,
+		},
+		{"delete", Cookie{Name: "a", MaxAge: -1}, "Set-Cookie: a=; Max-Age=0\r\n", nil},
+		{"strict", Cookie{Name: "a", Value: "1", SameSite: SameSiteStrict}, "Set-Cookie: a=1; SameSite=Strict\r\n", nil},
+		{"none", Cookie{Name: "a", Value: "1", Secure: true, SameSite: SameSiteNone}, "Set-Cookie: a=1; Secure; SameSite=None\r\n", nil},
+		{"empty name", Cookie{Value: "1"}, "", ErrInvalidCookieName},
+		{"invalid name", Cookie{Name: "a b", Value: "1"}, "", ErrInvalidCookieName},
+		{"separator in name", Cookie{Name: "a=b", Value: "1"}, "", ErrInvalidCookieName},
+		{"space in value", Cookie{Name: "a", Value: "hello world"}, "", ErrInvalidCookieValue},
+		{"semicolon in value", Cookie{Name: "a", Value: "1; Secure"}, "", ErrInvalidCookieValue},
+		{"non-ascii value", Cookie{Name: "a", Value: "caf\xc3\xa9"}, "", ErrInvalidCookieValue},
+		{"invalid path", Cookie{Name: "a", Value: "1", Path: "/; Domain=evil.com"}, "", ErrInvalidCookieAttribute},
+		{"invalid domain", Cookie{Name: "a", Value: "1", Domain: "example.com\r\nX-Injected: 1"}, "", ErrInvalidCookieAttribute},
+		{"partitioned without secure", Cookie{Name: "a", Value: "1", Partitioned: true}, "", ErrInvalidCookieAttribute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			resp := GetResponse(&buffer)
+			defer PutResponse(resp)
+
+			err := resp.WriteSetCookie(&tt.cookie)
+			if err != tt.wantErr {
+				t.Fatalf("WriteSetCookie() error = %v, want %v", err, tt.wantErr)
+			}
+			resp.Flush()
+			if buffer.String() != tt.want {
+				t.Errorf("WriteSetCookie() wrote %q, want %q", buffer.String(), tt.want)
+			}
+		})
+	}
+}
+
+// failOnceWriter fails its first write.
+type failOnceWriter struct {
+	failed bool
+}
+
+var errFailOnce = errors.New("write failed")
+
+func (w *failOnceWriter) Write(b []byte) (int, error) {
+	if !w.failed {
+		w.failed = true
+		return 0, errFailOnce
+	}
+	return len(b), nil
+}
+
+func Test_Response_WriteSetCookie_WriteError(t *testing.T) {
+	resp := GetResponse(&failOnceWriter{})
+	defer PutResponse(resp)
+
+	// The name overflows the buffer, whose flush fails; the following writes succeed
+	cookie := Cookie{Name: strings.Repeat("a", cap(resp.buf)), Value: "1", Path: "/"}
+	if err := resp.WriteSetCookie(&cookie); err != errFailOnce {
+		t.Errorf("WriteSetCookie() error = %v, want %v", err, errFailOnce)
+	}
+}
+
+func Benchmark_Response_WriteSetCookie(b *testing.B) {
+	b.ReportAllocs()
+	cookie := Cookie{
+		Name: "session", Value: "0123456789abcdef", Path: "/",
+		Expires: time.Now().Add(24 * time.Hour), Secure: true, HttpOnly: true, SameSite: SameSiteLax,
+	}
+	b.RunParallel(func(p *testing.PB) {
+		resp := GetResponse(io.Discard)
+		defer PutResponse(resp)
+		for p.Next() {
+			resp.WriteSetCookie(&cookie)
+			resp.Reset()
+		}
+	})
+}
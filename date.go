@@ -0,0 +1,171 @@
+package h1
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidDate = errors.New("invalid http date")
+
+// imfFixdate is the preferred HTTP date format (RFC 9110 Section 5.6.7).
+const imfFixdate = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// HTTPDateLen is the length of a date written by AppendHTTPDate.
+const HTTPDateLen = len(imfFixdate)
+
+var dayNames = [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+var longDayNames = [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+var monthNames = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// AppendHTTPDate appends t in UTC as an IMF-fixdate ("Sun, 06 Nov 1994 08:49:37 GMT").
+func AppendHTTPDate(dst []byte, t time.Time) []byte {
+	t = t.UTC()
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+
+	dst = append(dst, dayNames[t.Weekday()]...)
+	dst = append(dst, ", "...)
+	dst = appendDigits2(dst, day)
+	dst = append(dst, ' ')
+	dst = append(dst, monthNames[month-1]...)
+	dst = append(dst, ' ')
+	dst = appendDigits2(dst, year/100%100)
+	dst = appendDigits2(dst, year%100)
+	dst = append(dst, ' ')
+	dst = appendDigits2(dst, hour)
+	dst = append(dst, ':')
+	dst = appendDigits2(dst, min)
+	dst = append(dst, ':')
+	dst = appendDigits2(dst, sec)
+	return append(dst, " GMT"...)
+}
+
+func appendDigits2(dst []byte, v int) []byte {
+	return append(dst, byte('0'+v/10), byte('0'+v%10))
+}
+
+// ParseHTTPDate parses an HTTP date in any of the three formats of RFC 9110 Section 5.6.7:
+//
+//	Sun, 06 Nov 1994 08:49:37 GMT  ; IMF-fixdate
+//	Sunday, 06-Nov-94 08:49:37 GMT ; obsolete RFC 850 format
+//	Sun Nov  6 08:49:37 1994       ; ANSI C's asctime() format
+//
+// The day name is not checked against the date. The result is in UTC.
+func ParseHTTPDate(b []byte) (time.Time, error) {
+	switch {
+	case len(b) == len(imfFixdate) && b[3] == ',':
+		return parseIMFFixdate(b)
+	case len(b) == len("Sun Nov  6 08:49:37 1994") && b[3] == ' ':
+		return parseAsctime(b)
+	default:
+		return parseRFC850(b)
+	}
+}
+
+// parseIMFFixdate parses "Sun, 06 Nov 1994 08:49:37 GMT".
+func parseIMFFixdate(b []byte) (time.Time, error) {
+	if lookupName(b[:3], dayNames[:]) < 0 || b[3] != ',' || b[4] != ' ' || b[7] != ' ' || b[11] != ' ' || b[16] != ' ' || string(b[25:]) != " GMT" {
+		return time.Time{}, ErrInvalidDate
+	}
+	day, ok := parseDigits(b[5:7])
+	if !ok {
+		return time.Time{}, ErrInvalidDate
+	}
+	year, ok := parseDigits(b[12:16])
+	if !ok {
+		return time.Time{}, ErrInvalidDate
+	}
+	return buildDate(year, b[8:11], day, b[17:25])
+}
+
+// parseRFC850 parses "Sunday, 06-Nov-94 08:49:37 GMT".
+func parseRFC850(b []byte) (time.Time, error) {
+	comma := indexByte(b, ',')
+	if comma < 0 || lookupName(b[:comma], longDayNames[:]) < 0 {
+		return time.Time{}, ErrInvalidDate
+	}
+	b = b[comma+1:]
+	if len(b) != len(" 06-Nov-94 08:49:37 GMT") || b[0] != ' ' || b[3] != '-' || b[7] != '-' || b[10] != ' ' || string(b[19:]) != " GMT" {
+		return time.Time{}, ErrInvalidDate
+	}
+	day, ok := parseDigits(b[1:3])
+	if !ok {
+		return time.Time{}, ErrInvalidDate
+	}
+	year, ok := parseDigits(b[8:10])
+	if !ok {
+		return time.Time{}, ErrInvalidDate
+	}
+
+	// A two digit year more than 50 years in the future is in the past century
+	current := time.Now().UTC().Year()
+	year += current / 100 * 100
+	if year > current+50 {
+		year -= 100
+	}
+	return buildDate(year, b[4:7], day, b[11:19])
+}
+
+// parseAsctime parses "Sun Nov  6 08:49:37 1994".
+func parseAsctime(b []byte) (time.Time, error) {
+	if lookupName(b[:3], dayNames[:]) < 0 || b[3] != ' ' || b[7] != ' ' || b[10] != ' ' || b[19] != ' ' {
+		return time.Time{}, ErrInvalidDate
+	}
+	dayDigits := b[8:10]
+	if dayDigits[0] == ' ' {
+		dayDigits = dayDigits[1:]
+	}
+	day, ok := parseDigits(dayDigits)
+	if !ok {
+		return time.Time{}, ErrInvalidDate
+	}
+	year, ok := parseDigits(b[20:24])
+	if !ok {
+		return time.Time{}, ErrInvalidDate
+	}
+	return buildDate(year, b[4:7], day, b[11:19])
+}
+
+// buildDate validates the month name and "hh:mm:ss" clock and returns the date in UTC.
+func buildDate(year int, monthName []byte, day int, clock []byte) (time.Time, error) {
+	month := lookupName(monthName, monthNames[:]) + 1
+	if month == 0 || day < 1 || day > 31 || clock[2] != ':' || clock[5] != ':' {
+		return time.Time{}, ErrInvalidDate
+	}
+	hour, ok1 := parseDigits(clock[0:2])
+	min, ok2 := parseDigits(clock[3:5])
+	sec, ok3 := parseDigits(clock[6:8])
+	if !ok1 || !ok2 || !ok3 || hour > 23 || min > 59 || sec > 60 {
+		return time.Time{}, ErrInvalidDate
+	}
+
+	// time.Date normalizes 31 Feb to 3 Mar. The seconds are added afterwards, a leap second may end the day.
+	t := time.Date(year, time.Month(month), day, hour, min, 0, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}, ErrInvalidDate
+	}
+	return t.Add(time.Duration(sec) * time.Second), nil
+}
+
+func lookupName(b []byte, names []string) int {
+	for i := range names {
+		if string(b) == names[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseDigits(b []byte) (int, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	v := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		v = v*10 + int(c-'0')
+	}
+	return v, true
+}
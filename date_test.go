@@ -0,0 +1,98 @@
+package h1
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_AppendHTTPDate(t *testing.T) {
+	tests := []time.Time{
+		time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC),
+		time.Date(2022, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		time.Date(2000, 2, 29, 0, 0, 0, 0, time.FixedZone("KST", 9*60*60)),
+		time.Date(9999, 1, 1, 1, 1, 1, 0, time.UTC),
+		time.Unix(0, 0),
+	}
+	for _, tm := range tests {
+		want := tm.UTC().Format(http.TimeFormat)
+		if got := string(AppendHTTPDate(nil, tm)); got != want {
+			t.Errorf("AppendHTTPDate(%v) = %q, want %q", tm, got, want)
+		}
+	}
+
+	buf := make([]byte, 0, HTTPDateLen)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = AppendHTTPDate(buf[:0], tests[0])
+	})
+	if allocs != 0 {
+		t.Errorf("AppendHTTPDate() allocates %v times", allocs)
+	}
+}
+
+func Test_ParseHTTPDate(t *testing.T) {
+	want := time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"Sun, 06 Nov 1994 08:49:37 GMT", want, false},
+		{"Sunday, 06-Nov-94 08:49:37 GMT", want, false},
+		{"Sun Nov  6 08:49:37 1994", want, false},
+		{"Sat, 31 Dec 2022 23:59:59 GMT", time.Date(2022, 12, 31, 23, 59, 59, 0, time.UTC), false},
+		{"Wednesday, 09-Nov-22 08:49:37 GMT", time.Date(2022, 11, 9, 8, 49, 37, 0, time.UTC), false},
+		{"Tue Nov 15 08:49:37 1994", time.Date(1994, 11, 15, 8, 49, 37, 0, time.UTC), false},
+
+		{"Sun, 06 Nov 1994 08:49:37 UTC", time.Time{}, true},
+		{"Xyz, 06 Nov 1994 08:49:37 GMT", time.Time{}, true},
+		{"Sun, 06 Foo 1994 08:49:37 GMT", time.Time{}, true},
+		{"Sun, 6 Nov 1994 08:49:37 GMT", time.Time{}, true},
+		{"Sun, 06 Nov 1994 24:49:37 GMT", time.Time{}, true},
+		{"Sun, 00 Nov 1994 08:49:37 GMT", time.Time{}, true},
+		{"Sun, 06 Nov 19x4 08:49:37 GMT", time.Time{}, true},
+		{"Sun, 06 Nov 1994 08-49-37 GMT", time.Time{}, true},
+		{"Sun, 06-Nov-94 08:49:37 GMT", time.Time{}, true},
+		{"Sunday, 06 Nov 1994 08:49:37 GMT", time.Time{}, true},
+		{"Funday, 06-Nov-94 08:49:37 GMT", time.Time{}, true},
+		{"Sun Nov 6 08:49:37 1994", time.Time{}, true},
+		{"Sun Nov  6 08:49:37 94", time.Time{}, true},
+		{"Sun Nov  x 08:49:37 1994", time.Time{}, true},
+		{"Sun! 06 Nov 1994X08:49:37 GMT", time.Time{}, true},
+		{"Sun, 06 Nov 1994X08:49:37 GMT", time.Time{}, true},
+		{"Sun,X06 Nov 1994 08:49:37 GMT", time.Time{}, true},
+		{"Sun, 31 Feb 1994 08:49:37 GMT", time.Time{}, true},
+		{"Sun, 29 Feb 1995 08:49:37 GMT", time.Time{}, true},
+		{"Thursday, 31-Apr-22 08:49:37 GMT", time.Time{}, true},
+		{"Sun Feb 30 08:49:37 1994", time.Time{}, true},
+		{"Thu, 29 Feb 1996 08:49:37 GMT", time.Date(1996, 2, 29, 8, 49, 37, 0, time.UTC), false},
+		{"Sat, 31 Dec 2016 23:59:60 GMT", time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseHTTPDate([]byte(tt.value))
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("ParseHTTPDate(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	value := []byte("Sunday, 06-Nov-94 08:49:37 GMT")
+	allocs := testing.AllocsPerRun(100, func() {
+		ParseHTTPDate(value)
+	})
+	if allocs != 0 {
+		t.Errorf("ParseHTTPDate() allocates %v times", allocs)
+	}
+}
+
+func Test_FastDateServer_Format(t *testing.T) {
+	fds := NewFastDateServer("h1")
+	defer fds.Stop()
+	date := string(fds.GetDate())
+	if len(date) != len("Date: ")+HTTPDateLen+len("\r\nServer: h1\r\n") || date[len("Date: ")+HTTPDateLen-4:len("Date: ")+HTTPDateLen] != " GMT" {
+		t.Errorf("GetDate() = %q", date)
+	}
+}
@@ -0,0 +1,155 @@
+package h1
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"sync"
+)
+
+// DefaultMaxDecompressedSize is the decompressed body limit used when RequestReader.MaxDecompressedSize is zero or negative.
+const DefaultMaxDecompressedSize = 32 << 20
+
+var ErrUnsupportedContentEncoding = errors.New("unsupported content encoding")
+var ErrDecompressedBodyTooLarge = errors.New("decompressed body too large")
+
+// ParseContentCoding parses a Content-Encoding header value.
+// Only a single gzip, x-gzip, deflate or identity coding is supported.
+func ParseContentCoding(value []byte) (ContentCoding, error) {
+	value = trimLWS(value)
+	switch {
+	case len(value) == 0 || stricmp(value, codingName[CodingIdentity]):
+		return CodingIdentity, nil
+	case stricmp(value, codingName[CodingGzip]) || stricmp(value, xGzip):
+		return CodingGzip, nil
+	case stricmp(value, codingName[CodingDeflate]):
+		return CodingDeflate, nil
+	}
+	return CodingIdentity, ErrUnsupportedContentEncoding
+}
+
+var gzipReaderPool sync.Pool
+var zlibReaderPool sync.Pool
+
+// DecompressReader decompresses a request body read through a BodyReader.
+type DecompressReader struct {
+	Body *BodyReader
+
+	coding    ContentCoding
+	zr        io.Reader
+	remaining int64
+}
+
+var decompressReaderPool = sync.Pool{
+	New: func() any {
+		return &DecompressReader{}
+	},
+}
+
+// DecodedBody returns the request body, decoded according to its Content-Encoding.
+// For gzip and deflate the body is decompressed by a pooled reader limited to MaxDecompressedSize bytes,
+// otherwise the BodyReader is returned as is.
+// ErrUnsupportedContentEncoding is returned for any other coding (respond with 415 Unsupported Media Type).
+func (r *RequestReader) DecodedBody() (io.ReadCloser, error) {
+	coding := CodingIdentity
+	if h, ok := r.Request.GetHeader(ContentEncodingHeader); ok {
+		var err error
+		coding, err = ParseContentCoding(h.RawValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	body := r.Body()
+	if coding == CodingIdentity {
+		return body, nil
+	}
+
+	d := decompressReaderPool.Get().(*DecompressReader)
+	d.Body = body
+	d.coding = coding
+	d.remaining = r.MaxDecompressedSize
+	if d.remaining <= 0 {
+		d.remaining = DefaultMaxDecompressedSize
+	}
+
+	switch coding {
+	case CodingGzip:
+		zr, ok := gzipReaderPool.Get().(*gzip.Reader)
+		var err error
+		if ok {
+			err = zr.Reset(body)
+		} else {
+			zr, err = gzip.NewReader(body)
+		}
+		if err != nil {
+			if zr != nil {
+				gzipReaderPool.Put(zr)
+			}
+			d.zr = nil
+			d.Close()
+			return nil, err
+		}
+		d.zr = zr
+	case CodingDeflate:
+		// "deflate" is the zlib format (RFC 9110, section 8.4.1.2)
+		zr, ok := zlibReaderPool.Get().(io.ReadCloser)
+		var err error
+		if ok {
+			err = zr.(zlib.Resetter).Reset(body, nil)
+		} else {
+			zr, err = zlib.NewReader(body)
+		}
+		if err != nil {
+			if zr != nil {
+				zlibReaderPool.Put(zr)
+			}
+			d.zr = nil
+			d.Close()
+			return nil, err
+		}
+		d.zr = zr
+	}
+
+	return d, nil
+}
+
+func (d *DecompressReader) Read(p []byte) (int, error) {
+	if d.remaining <= 0 {
+		// Check if there is more data than allowed
+		var probe [1]byte
+		n, err := d.zr.Read(probe[:])
+		if n > 0 {
+			return 0, ErrDecompressedBodyTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > d.remaining {
+		p = p[:d.remaining]
+	}
+	n, err := d.zr.Read(p)
+	d.remaining -= int64(n)
+	return n, err
+}
+
+// Close returns the decompressor and the BodyReader to their pools.
+func (d *DecompressReader) Close() error {
+	switch zr := d.zr.(type) {
+	case *gzip.Reader:
+		gzipReaderPool.Put(zr)
+	case io.ReadCloser:
+		zr.Close()
+		zlibReaderPool.Put(zr)
+	}
+
+	var err error
+	if d.Body != nil {
+		err = d.Body.Close()
+	}
+
+	*d = DecompressReader{}
+	decompressReaderPool.Put(d)
+	return err
+}
@@ -0,0 +1,95 @@
+package h1
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func compressForTest(t testing.TB, coding string, data []byte) []byte {
+	var buffer bytes.Buffer
+	var zw io.WriteCloser
+	switch coding {
+	case "gzip":
+		zw = gzip.NewWriter(&buffer)
+	case "deflate":
+		zw = zlib.NewWriter(&buffer)
+	case "raw deflate":
+		zw, _ = flate.NewWriter(&buffer, flate.DefaultCompression)
+	default:
+		return data
+	}
+	zw.Write(data)
+	zw.Close()
+	return buffer.Bytes()
+}
+
+func Test_RequestReader_DecodedBody(t *testing.T) {
+	payload := []byte(strings.Repeat("telemetry ", 5000))
+
+	tests := []struct {
+		name     string
+		encoding string
+		coding   string
+		maxSize  int64
+		wantErr  error
+	}{
+		{"identity", "", "", 0, nil},
+		{"explicit identity", "identity", "", 0, nil},
+		{"gzip", "gzip", "gzip", 0, nil},
+		{"x-gzip", "x-gzip", "gzip", 0, nil},
+		{"deflate", "Deflate", "deflate", 0, nil},
+		{"raw deflate", "deflate", "raw deflate", 0, zlib.ErrHeader},
+		{"size limit", "gzip", "gzip", 1000, ErrDecompressedBodyTooLarge},
+		{"exact size limit", "gzip", "gzip", int64(len(payload)), nil},
+		{"negative size limit", "gzip", "gzip", -1, nil},
+		{"unsupported", "br", "", 0, ErrUnsupportedContentEncoding},
+		{"stacked codings", "gzip, gzip", "gzip", 0, ErrUnsupportedContentEncoding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := compressForTest(t, tt.coding, payload)
+			header := ""
+			if tt.encoding != "" {
+				header = "Content-Encoding: " + tt.encoding + "\r\n"
+			}
+			r := newTestRequestReader(fmt.Sprintf("POST /ingest HTTP/1.1\r\n%sContent-Length: %d\r\n\r\n%s", header, len(body), body))
+			r.MaxDecompressedSize = tt.maxSize
+			if _, err := r.Next(); err != nil {
+				t.Fatal(err)
+			}
+
+			rc, err := r.DecodedBody()
+			if err == nil {
+				var got []byte
+				got, err = io.ReadAll(rc)
+				rc.Close()
+				if err == nil && !bytes.Equal(got, payload) {
+					t.Errorf("body = %d bytes, want %d bytes", len(got), len(payload))
+				}
+			}
+			if err != tt.wantErr {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_RequestReader_DecodedBody_Invalid(t *testing.T) {
+	r := newTestRequestReader("POST / HTTP/1.1\r\nContent-Encoding: gzip\r\nContent-Length: 5\r\n\r\nhello")
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DecodedBody(); err == nil {
+		t.Error("DecodedBody() error = nil, want invalid gzip header error")
+	}
+	if got := ErrorStatus(ErrUnsupportedContentEncoding); got != 415 {
+		t.Errorf("ErrorStatus() = %d, want 415", got)
+	}
+}
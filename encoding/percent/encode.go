@@ -0,0 +1,83 @@
+package percent
+
+// Set selects the characters that are left unescaped by Encode.
+// All other bytes are percent-encoded.
+type Set uint8
+
+const (
+	// PathSegment keeps pchar except "/" (RFC 3986 Section 3.3).
+	PathSegment Set = 1 << iota
+	// QueryComponent keeps the characters that are safe inside a query key or value.
+	// "&", "=", "+" and "#" are escaped.
+	QueryComponent
+	// Userinfo keeps unreserved, sub-delims and ":" (RFC 3986 Section 3.2.1).
+	Userinfo
+	// Fragment keeps pchar, "/" and "?" (RFC 3986 Section 3.5).
+	Fragment
+)
+
+const upperhex = "0123456789ABCDEF"
+
+var encodeTable = [256]Set{}
+
+var _ = func() int {
+	const unreserved = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-._~"
+	const subDelims = "!$&'()*+,;="
+
+	for _, c := range []byte(unreserved) {
+		encodeTable[c] = PathSegment | QueryComponent | Userinfo | Fragment
+	}
+	for _, c := range []byte(subDelims) {
+		encodeTable[c] |= PathSegment | Userinfo | Fragment
+	}
+	for _, c := range []byte("!$'()*,;:@/?") {
+		encodeTable[c] |= QueryComponent
+	}
+	encodeTable[':'] |= PathSegment | Userinfo | Fragment
+	encodeTable['@'] |= PathSegment | Fragment
+	encodeTable['/'] |= Fragment
+	encodeTable['?'] |= Fragment
+
+	return 0
+}()
+
+func shouldEscape(c byte, set Set) bool {
+	return encodeTable[c]&set == 0
+}
+
+// EncodedLen returns the length of src after encoding with set.
+func EncodedLen(src []byte, set Set) int {
+	n := len(src)
+	for _, c := range src {
+		if shouldEscape(c, set) {
+			n += 2
+		}
+	}
+	return n
+}
+
+// Encode writes the encoded src to dst and returns the number of bytes written.
+// dst must be at least EncodedLen(src, set) bytes long.
+func Encode(dst, src []byte, set Set) int {
+	j := 0
+	for _, c := range src {
+		if shouldEscape(c, set) {
+			dst[j] = '%'
+			dst[j+1] = upperhex[c>>4]
+			dst[j+2] = upperhex[c&15]
+			j += 3
+			continue
+		}
+		dst[j] = c
+		j++
+	}
+	return j
+}
+
+// AppendEncode appends the encoded src to dst and returns the extended buffer.
+func AppendEncode(dst, src []byte, set Set) []byte {
+	n := EncodedLen(src, set)
+	dst = grow(dst, n)
+	Encode(dst[len(dst):len(dst)+n], src, set)
+	return dst[:len(dst)+n]
+}
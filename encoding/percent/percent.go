@@ -1,20 +1,93 @@
 package percent
 
+import "errors"
+
+var ErrInvalidEscape = errors.New("percent: invalid escape sequence")
+
+var hexTable = [256]bool{}
+
+var _ = func() int {
+	for _, c := range []byte("0123456789abcdefABCDEF") {
+		hexTable[c] = true
+	}
+	return 0
+}()
+
+// Decode decodes buffer in place and returns the decoded bytes.
+// Invalid or incomplete escape sequences are kept as is.
 func Decode(buffer []byte) []byte {
+	n, _ := decode(buffer, buffer, false, false)
+	return buffer[:n]
+}
+
+// DecodeStrict decodes buffer in place and returns the decoded bytes.
+// ErrInvalidEscape is returned if buffer contains an invalid or incomplete escape sequence.
+func DecodeStrict(buffer []byte) ([]byte, error) {
+	n, err := decode(buffer, buffer, false, true)
+	return buffer[:n], err
+}
+
+// DecodeForm is like Decode, but also decodes '+' as a space (application/x-www-form-urlencoded).
+func DecodeForm(buffer []byte) []byte {
+	n, _ := decode(buffer, buffer, true, false)
+	return buffer[:n]
+}
+
+// DecodeFormStrict is like DecodeStrict, but also decodes '+' as a space (application/x-www-form-urlencoded).
+func DecodeFormStrict(buffer []byte) ([]byte, error) {
+	n, err := decode(buffer, buffer, true, true)
+	return buffer[:n], err
+}
+
+// AppendDecode appends the strictly decoded src to dst and returns the extended buffer.
+func AppendDecode(dst, src []byte) ([]byte, error) {
+	return appendDecode(dst, src, false)
+}
+
+// AppendDecodeForm appends the strictly decoded src to dst, decoding '+' as a space.
+func AppendDecodeForm(dst, src []byte) ([]byte, error) {
+	return appendDecode(dst, src, true)
+}
+
+func appendDecode(dst, src []byte, plusAsSpace bool) ([]byte, error) {
+	dst = grow(dst, len(src))
+	n, err := decode(dst[len(dst):len(dst)+len(src)], src, plusAsSpace, true)
+	return dst[:len(dst)+n], err
+}
+
+// decode writes the decoded src to dst and returns the number of bytes written.
+// dst may be src itself since the write index never passes the read index.
+func decode(dst, src []byte, plusAsSpace, strict bool) (int, error) {
 	writeIndex := 0
 
-	for readIndex := 0; readIndex < len(buffer); readIndex++ {
-		if buffer[readIndex] == '%' {
-			if readIndex+2 >= len(buffer) {
+	for readIndex := 0; readIndex < len(src); readIndex++ {
+		switch c := src[readIndex]; {
+		case c == '%':
+			if readIndex+2 >= len(src) || !hexTable[src[readIndex+1]] || !hexTable[src[readIndex+2]] {
+				if strict {
+					return writeIndex, ErrInvalidEscape
+				}
+				dst[writeIndex] = c
 				break
 			}
-			buffer[writeIndex] = DecodeHexTwo(buffer[readIndex+1], buffer[readIndex+2])
+			dst[writeIndex] = DecodeHexTwo(src[readIndex+1], src[readIndex+2])
 			readIndex += 2
-		} else {
-			buffer[writeIndex] = buffer[readIndex]
+		case c == '+' && plusAsSpace:
+			dst[writeIndex] = ' '
+		default:
+			dst[writeIndex] = c
 		}
 		writeIndex++
 	}
 
-	return buffer[:writeIndex]
+	return writeIndex, nil
+}
+
+func grow(b []byte, n int) []byte {
+	if cap(b)-len(b) >= n {
+		return b
+	}
+	nb := make([]byte, len(b), 2*cap(b)+n)
+	copy(nb, b)
+	return nb
 }
@@ -44,3 +44,94 @@ func TestDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		buffer     string
+		want       string
+		wantStrict string
+		wantErr    bool
+	}{
+		{"valid", "a%20b", "a b", "a b", false},
+		{"invalid hex", "a%zzb", "a%zzb", "a", true},
+		{"half invalid hex", "%4g", "%4g", "", true},
+		{"trailing percent", "abc%", "abc%", "abc", true},
+		{"trailing incomplete", "abc%4", "abc%4", "abc", true},
+		{"plus", "a+b", "a+b", "a+b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Decode([]byte(tt.buffer)); string(got) != tt.want {
+				t.Errorf("Decode() = %q, want %q", got, tt.want)
+			}
+			got, err := DecodeStrict([]byte(tt.buffer))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeStrict() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.wantStrict {
+				t.Errorf("DecodeStrict() = %q, want %q", got, tt.wantStrict)
+			}
+		})
+	}
+}
+
+func TestDecodeForm(t *testing.T) {
+	if got := DecodeForm([]byte("hello+world%2B1")); string(got) != "hello world+1" {
+		t.Errorf("DecodeForm() = %q, want %q", got, "hello world+1")
+	}
+	if _, err := DecodeFormStrict([]byte("a+%z")); err != ErrInvalidEscape {
+		t.Errorf("DecodeFormStrict() error = %v, want %v", err, ErrInvalidEscape)
+	}
+
+	dst := []byte("q=")
+	dst, err := AppendDecodeForm(dst, []byte("a+b%21"))
+	if err != nil || string(dst) != "q=a b!" {
+		t.Errorf("AppendDecodeForm() = %q, %v", dst, err)
+	}
+	dst, err = AppendDecode(dst[:0], []byte("a+b%21"))
+	if err != nil || string(dst) != "a+b!" {
+		t.Errorf("AppendDecode() = %q, %v", dst, err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		set  Set
+		want string
+	}{
+		{"unreserved", "aZ09-._~", PathSegment, "aZ09-._~"},
+		{"path segment", "a b/c:d@e;f", PathSegment, "a%20b%2Fc:d@e;f"},
+		{"query component", "a b&c=d+e#f/g?h", QueryComponent, "a%20b%26c%3Dd%2Be%23f/g?h"},
+		{"userinfo", "user:p@ss/w", Userinfo, "user:p%40ss%2Fw"},
+		{"fragment", "sec 1/a?b#c", Fragment, "sec%201/a?b%23c"},
+		{"non-ascii", "🎉", PathSegment, "%F0%9F%8E%89"},
+		{"control", "\x00\x7f", QueryComponent, "%00%7F"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AppendEncode(nil, []byte(tt.src), tt.set)
+			if string(got) != tt.want {
+				t.Errorf("AppendEncode() = %q, want %q", got, tt.want)
+			}
+			if n := EncodedLen([]byte(tt.src), tt.set); n != len(tt.want) {
+				t.Errorf("EncodedLen() = %d, want %d", n, len(tt.want))
+			}
+			decoded, err := DecodeStrict(got)
+			if err != nil || string(decoded) != tt.src {
+				t.Errorf("DecodeStrict(AppendEncode()) = %q, %v, want %q", decoded, err, tt.src)
+			}
+		})
+	}
+}
+
+func BenchmarkAppendEncode(b *testing.B) {
+	b.ReportAllocs()
+	src := []byte("http://example.com/cb?state=a b&x=1")
+	dst := make([]byte, 0, 128)
+	for i := 0; i < b.N; i++ {
+		dst = AppendEncode(dst[:0], src, QueryComponent)
+	}
+}
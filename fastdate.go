@@ -7,73 +7,191 @@ import (
 	"unsafe"
 )
 
+// Clock provides the current time to a FastDateServer.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the wall clock.
+var SystemClock Clock = systemClock{}
+
+// FixedClock is a Clock that always returns the same time.
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// FastDateServer keeps the "Date: ...\r\nServer: ...\r\n" header lines formatted, updating them once per second.
+// Either line can be left out with NewFastDateServerOptions.
+// The updating goroutine is started by the first GetDate (or Start) and stopped by Stop.
 type FastDateServer struct {
 	serverName string
+	noDate     bool
+	clock      Clock
+	manual     bool // test mode or no Date header, never started
 
 	dates []*[]byte
 	index int
 
-	current *[]byte
+	current unsafe.Pointer // *[]byte
+
+	mu    sync.Mutex
+	state uint32
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+const (
+	dateServerIdle uint32 = iota
+	dateServerRunning
+	dateServerStopped
+)
+
+type FastDateServerOptions struct {
+	// ServerName is the value of the Server header. The header is omitted if empty.
+	ServerName string
+
+	// NoDate omits the Date header. The header lines never change then, so no goroutine is started.
+	NoDate bool
 
-	once sync.Once
-	stop chan struct{}
+	// Clock provides the time (SystemClock if nil)
+	Clock Clock
 }
 
+// NewFastDateServer returns a FastDateServer using the wall clock.
 func NewFastDateServer(serverName string) *FastDateServer {
+	return NewFastDateServerClock(serverName, SystemClock)
+}
+
+// NewFastDateServerClock returns a FastDateServer reading the time from clock once per second.
+func NewFastDateServerClock(serverName string, clock Clock) *FastDateServer {
+	return NewFastDateServerOptions(FastDateServerOptions{ServerName: serverName, Clock: clock})
+}
+
+// NewFastDateServerOptions returns a FastDateServer writing the header lines selected by opts.
+func NewFastDateServerOptions(opts FastDateServerOptions) *FastDateServer {
+	clock := opts.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	serverName := opts.ServerName
+
 	fds := &FastDateServer{
 		dates:      make([]*[]byte, 32),
 		serverName: serverName,
+		noDate:     opts.NoDate,
+		manual:     opts.NoDate,
+		clock:      clock,
 		index:      0,
-		stop:       make(chan struct{}),
 	}
 
 	for i := range fds.dates {
-		v := make([]byte, 0, len("Server: ")+len(serverName)+len("\r\nDate: ")+len(time.RFC1123)*2+len("\r\n\r\n"))
+		v := make([]byte, 0, len("Server: ")+len(serverName)+len("\r\nDate: ")+HTTPDateLen+len("\r\n\r\n"))
 		fds.dates[i] = &v
 	}
 
-	fds.updateDate(time.Now().UTC())
+	fds.Update()
 
 	return fds
 }
 
+// NewTestFastDateServer returns a FastDateServer for deterministic output in tests.
+// It never starts a goroutine, the date only changes when Update is called.
+func NewTestFastDateServer(serverName string, clock Clock) *FastDateServer {
+	fds := NewFastDateServerClock(serverName, clock)
+	fds.manual = true
+	return fds
+}
+
+// Update formats the current time of the clock immediately.
+func (fds *FastDateServer) Update() {
+	fds.mu.Lock()
+	fds.updateDate(fds.clock.Now().UTC())
+	fds.mu.Unlock()
+}
+
 func (fds *FastDateServer) updateDate(date time.Time) {
 	new := fds.dates[fds.index%len(fds.dates)]
 	fds.index++
 
 	*new = (*new)[:0]
-	*new = append(*new, "Date: "...)
-	*new = append(*new, date.Format(time.RFC1123)...)
-	*new = append(*new, "\r\nServer: "...)
-	*new = append(*new, fds.serverName...)
-	*new = append(*new, "\r\n"...)
+	if !fds.noDate {
+		*new = append(*new, "Date: "...)
+		*new = AppendHTTPDate(*new, date)
+		*new = append(*new, "\r\n"...)
+	}
+	if len(fds.serverName) > 0 {
+		*new = append(*new, "Server: "...)
+		*new = append(*new, fds.serverName...)
+		*new = append(*new, "\r\n"...)
+	}
 
-	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&fds.current)), unsafe.Pointer(new))
+	atomic.StorePointer(&fds.current, unsafe.Pointer(new))
 }
 
+// GetDate returns the formatted header lines, starting the updating goroutine on first use.
+// The returned slice must not be modified.
 func (fds *FastDateServer) GetDate() []byte {
-	return *fds.current
+	if atomic.LoadUint32(&fds.state) == dateServerIdle && !fds.manual {
+		fds.Start()
+	}
+	return *(*[]byte)(atomic.LoadPointer(&fds.current))
 }
 
+// Start starts the updating goroutine. It does nothing if it is already running or in test mode.
 func (fds *FastDateServer) Start() {
-	fds.once.Do(func() {
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				fds.updateDate(time.Now().UTC())
-			case <-fds.stop:
-				return
-			}
+	fds.mu.Lock()
+	defer fds.mu.Unlock()
+
+	if fds.manual || fds.state == dateServerRunning {
+		return
+	}
+
+	// The date may be stale after a Stop
+	fds.updateDate(fds.clock.Now().UTC())
+
+	fds.stop = make(chan struct{})
+	fds.done = make(chan struct{})
+	atomic.StoreUint32(&fds.state, dateServerRunning)
+	go fds.run(fds.stop, fds.done)
+}
+
+func (fds *FastDateServer) run(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fds.Update()
+		case <-stop:
+			return
 		}
-	})
+	}
 }
 
+// Stop stops the updating goroutine and waits for it to exit. GetDate keeps returning the last date.
+// Stop may be called any number of times, Start restarts the goroutine.
 func (fds *FastDateServer) Stop() {
-	fds.stop <- struct{}{}
+	fds.mu.Lock()
+	if fds.state != dateServerRunning {
+		atomic.StoreUint32(&fds.state, dateServerStopped)
+		fds.mu.Unlock()
+		return
+	}
 	close(fds.stop)
+	done := fds.done
+	atomic.StoreUint32(&fds.state, dateServerStopped)
+	fds.mu.Unlock()
 
-	fds.once = sync.Once{}
+	<-done
 }
@@ -0,0 +1,119 @@
+package h1
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_FastDateServer_FixedClock(t *testing.T) {
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	want := "Date: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\n"
+	if got := string(fds.GetDate()); got != want {
+		t.Errorf("GetDate() = %q, want %q", got, want)
+	}
+}
+
+// stepClock advances by one second on every call to Now.
+type stepClock struct {
+	unix int64
+}
+
+func (c *stepClock) Now() time.Time {
+	return time.Unix(atomic.AddInt64(&c.unix, 1), 0)
+}
+
+func Test_FastDateServer_TestMode(t *testing.T) {
+	clock := &stepClock{unix: testDate.Unix() - 1}
+	fds := NewTestFastDateServer("h1", clock)
+
+	first := string(fds.GetDate())
+	if want := "Date: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\n"; first != want {
+		t.Fatalf("GetDate() = %q, want %q", first, want)
+	}
+	if atomic.LoadUint32(&fds.state) != dateServerIdle {
+		t.Error("test mode started the updating goroutine")
+	}
+	if got := string(fds.GetDate()); got != first {
+		t.Errorf("GetDate() changed without Update: %q", got)
+	}
+
+	fds.Update()
+	if want := "Date: Sun, 06 Nov 1994 08:49:38 GMT\r\nServer: h1\r\n"; string(fds.GetDate()) != want {
+		t.Errorf("GetDate() after Update = %q, want %q", fds.GetDate(), want)
+	}
+}
+
+func Test_FastDateServer_Lifecycle(t *testing.T) {
+	fds := NewFastDateServer("h1")
+	if atomic.LoadUint32(&fds.state) != dateServerIdle {
+		t.Fatal("NewFastDateServer started the updating goroutine")
+	}
+
+	// Lazy start
+	fds.GetDate()
+	if atomic.LoadUint32(&fds.state) != dateServerRunning {
+		t.Fatal("GetDate did not start the updating goroutine")
+	}
+
+	// Idempotent Start and Stop, including concurrent calls
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			fds.Start()
+		}()
+		go func() {
+			defer wg.Done()
+			fds.Stop()
+		}()
+	}
+	wg.Wait()
+
+	fds.Stop()
+	fds.Stop()
+	if atomic.LoadUint32(&fds.state) != dateServerStopped {
+		t.Fatal("Stop did not stop the updating goroutine")
+	}
+
+	// No restart after Stop
+	fds.GetDate()
+	if atomic.LoadUint32(&fds.state) != dateServerStopped {
+		t.Error("GetDate restarted a stopped FastDateServer")
+	}
+
+	fds.Start()
+	if atomic.LoadUint32(&fds.state) != dateServerRunning {
+		t.Error("Start did not restart the updating goroutine")
+	}
+	fds.Stop()
+}
+
+func Test_FastDateServer_Options(t *testing.T) {
+	tests := []struct {
+		opts FastDateServerOptions
+		want string
+	}{
+		{FastDateServerOptions{ServerName: "edge", Clock: FixedClock(testDate)}, "Date: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: edge\r\n"},
+		{FastDateServerOptions{Clock: FixedClock(testDate)}, "Date: Sun, 06 Nov 1994 08:49:37 GMT\r\n"},
+		{FastDateServerOptions{ServerName: "edge", NoDate: true}, "Server: edge\r\n"},
+		{FastDateServerOptions{NoDate: true}, ""},
+	}
+
+	for _, tt := range tests {
+		fds := NewFastDateServerOptions(tt.opts)
+		if got := string(fds.GetDate()); got != tt.want {
+			t.Errorf("GetDate() = %q, want %q", got, tt.want)
+		}
+		fds.Stop()
+	}
+
+	// Without a Date header the lines never change
+	fds := NewFastDateServerOptions(FastDateServerOptions{ServerName: "edge", NoDate: true})
+	fds.GetDate()
+	if atomic.LoadUint32(&fds.state) != dateServerIdle {
+		t.Error("NoDate started the updating goroutine")
+	}
+}
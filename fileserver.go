@@ -0,0 +1,372 @@
+package h1
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-www/h1/encoding/percent"
+)
+
+// FileServer serves the files of a directory to GET and HEAD requests.
+type FileServer struct {
+	// Root is the directory files are served from (the working directory if empty).
+	// Neither request paths nor symbolic links leave it.
+	Root string
+
+	// Prefix is stripped from the request path before looking up a file (e.g. "/static").
+	Prefix string
+
+	// IndexFiles are tried in order when a directory is requested.
+	// Directories without an index file are not listed.
+	IndexFiles []string
+}
+
+// NewFileServer returns a FileServer for root with "index.html" as its index file.
+func NewFileServer(root string) *FileServer {
+	return &FileServer{
+		Root:       root,
+		IndexFiles: []string{"index.html"},
+	}
+}
+
+var etagHeader = []byte("ETag: ")
+var lastModifiedHeader = []byte("Last-Modified: ")
+var locationHeader = []byte("Location: ")
+var allowGetHeadHeader = []byte("Allow: GET, HEAD\r\n")
+var textPlainHeader = []byte("Content-Type: text/plain; charset=utf-8\r\n")
+
+// Serve is a Handler serving the file named by the request path.
+func (fs *FileServer) Serve(resp *Response, req *RequestReader) error {
+	r := &req.Request
+	if r.Method != MethodGET && r.Method != MethodHEAD {
+		return writeStatusText(resp, r, 405, allowGetHeadHeader)
+	}
+
+	buffer := GetBuffer()
+	defer PutBuffer(buffer)
+
+	name, ok := fs.resolve(buffer, r.URI.RawPath)
+	if !ok {
+		return writeStatusText(resp, r, 400, nil)
+	}
+	if len(name) == 0 {
+		return writeStatusText(resp, r, 404, nil)
+	}
+
+	f, err := fs.open(name)
+	if err != nil {
+		return writeOpenError(resp, r, err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return writeOpenError(resp, r, err)
+	}
+
+	if st.IsDir() {
+		// Relative links in the index file need the trailing slash
+		if r.URI.RawPath[len(r.URI.RawPath)-1] != '/' {
+			return writeDirectoryRedirect(resp, r)
+		}
+
+		var index *os.File
+		for _, indexFile := range fs.IndexFiles {
+			index, st, err = fs.openIndexFile(filepath.Join(name, indexFile))
+			if err == nil {
+				name = indexFile
+				break
+			}
+		}
+		if index == nil {
+			return writeStatusText(resp, r, 404, nil)
+		}
+		defer index.Close()
+		f = index
+	}
+
+	return serveFile(resp, r, f, st, fileContentType(name), buffer)
+}
+
+// resolve decodes and cleans the request path and maps it to a file below Root.
+// ok is false for a malformed path. An empty name is returned for a path outside Prefix.
+func (fs *FileServer) resolve(buffer *[]byte, rawPath []byte) (name string, ok bool) {
+	p, err := percent.AppendDecode((*buffer)[:0], rawPath)
+	*buffer = p
+	if err != nil || len(p) == 0 || p[0] != '/' || indexByte(p, 0) != -1 {
+		return "", false
+	}
+	if filepath.Separator != '/' && indexByte(p, filepath.Separator) != -1 {
+		return "", false
+	}
+
+	// Cleaning a rooted path removes every ".." element, so the result stays below Root
+	clean := path.Clean(bytesToString(p))
+	if len(fs.Prefix) > 0 {
+		prefix := strings.TrimSuffix(fs.Prefix, "/")
+		if !strings.HasPrefix(clean, prefix) || (len(clean) > len(prefix) && clean[len(prefix)] != '/') {
+			return "", true
+		}
+		clean = clean[len(prefix):]
+	}
+
+	return filepath.Join(fs.root(), filepath.FromSlash(clean)), true
+}
+
+func (fs *FileServer) root() string {
+	if len(fs.Root) == 0 {
+		return "."
+	}
+	return fs.Root
+}
+
+// open opens the file name below Root. A symbolic link leading outside Root is reported as os.ErrNotExist.
+func (fs *FileServer) open(name string) (*os.File, error) {
+	target, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(fs.root())
+	if err != nil {
+		return nil, err
+	}
+	if !isBelow(target, root) {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(target)
+}
+
+// isBelow reports whether the path name is dir or inside it. Both paths must be free of symbolic links.
+func isBelow(name, dir string) bool {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return false
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	if name == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(name, dir)
+}
+
+func (fs *FileServer) openIndexFile(name string) (*os.File, os.FileInfo, error) {
+	f, err := fs.open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := f.Stat()
+	if err != nil || st.IsDir() {
+		f.Close()
+		return nil, nil, os.ErrNotExist
+	}
+	return f, st, nil
+}
+
+func writeOpenError(resp *Response, r *Request, err error) error {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return writeStatusText(resp, r, 404, nil)
+	case errors.Is(err, os.ErrPermission):
+		return writeStatusText(resp, r, 403, nil)
+	default:
+		return writeStatusText(resp, r, 500, nil)
+	}
+}
+
+// writeStatusText writes a plain text response containing the status code and reason phrase.
+func writeStatusText(resp *Response, r *Request, status int, extraHeaders []byte) error {
+	line := GetStatusLine(status)
+	text := line[len("HTTP/1.1 ") : len(line)-len("\r\n")]
+
+	resp.ContentLength = int64(len(text))
+	err := resp.WriteHeader(status)
+	if err != nil {
+		return err
+	}
+	resp.Write(textPlainHeader)
+	resp.Write(extraHeaders)
+	err = resp.EndHeader()
+	if err != nil {
+		return err
+	}
+	if r.Method != MethodHEAD {
+		_, err = resp.Write(text)
+	}
+	return err
+}
+
+func writeDirectoryRedirect(resp *Response, r *Request) error {
+	resp.ContentLength = 0
+	err := resp.WriteHeader(301)
+	if err != nil {
+		return err
+	}
+	writeSlashLocation(resp, r, true)
+	return resp.EndHeader()
+}
+
+// writeSlashLocation writes a Location header for the request path with a trailing slash added or removed.
+// Like net/http's localRedirect, the location is relative to the request path:
+// an absolute "//host/" built from a path such as "//host" would point to another host.
+func writeSlashLocation(resp *Response, r *Request, addSlash bool) {
+	raw := r.URI.RawPath
+	if !addSlash {
+		raw = raw[:len(raw)-1]
+	}
+	segment := raw[bytes.LastIndexByte(raw, '/')+1:]
+
+	resp.Write(locationHeader)
+	if !addSlash {
+		resp.WriteString("../")
+	} else if indexByte(segment, ':') != -1 {
+		// "a:b/" would be read as a URI with the scheme "a"
+		resp.WriteString("./")
+	}
+	resp.Write(segment)
+	if addSlash {
+		resp.WriteString("/")
+	}
+	if len(r.URI.RawQuery) > 0 {
+		resp.WriteString("?")
+		resp.Write(r.URI.RawQuery)
+	}
+	resp.Write(crlf)
+}
+
+// Content types of common static assets. Other extensions are looked up with mime.TypeByExtension.
+var fileContentTypes = map[string]string{
+	".html":  "text/html; charset=utf-8",
+	".htm":   "text/html; charset=utf-8",
+	".css":   "text/css; charset=utf-8",
+	".js":    "text/javascript; charset=utf-8",
+	".mjs":   "text/javascript; charset=utf-8",
+	".json":  "application/json",
+	".txt":   "text/plain; charset=utf-8",
+	".xml":   "text/xml; charset=utf-8",
+	".svg":   "image/svg+xml",
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".webp":  "image/webp",
+	".avif":  "image/avif",
+	".ico":   "image/x-icon",
+	".wasm":  "application/wasm",
+	".pdf":   "application/pdf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".mp4":   "video/mp4",
+	".webm":  "video/webm",
+	".mp3":   "audio/mpeg",
+	".zip":   "application/zip",
+	".gz":    "application/gzip",
+}
+
+func fileContentType(name string) string {
+	ext := filepath.Ext(name)
+	if t, ok := fileContentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); len(t) > 0 {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// appendETag appends a validator derived from the modification time and size of a file.
+func appendETag(dst []byte, size int64, modtime time.Time) []byte {
+	dst = append(dst, '"')
+	dst = strconv.AppendInt(dst, modtime.UnixNano(), 16)
+	dst = append(dst, '-')
+	dst = strconv.AppendInt(dst, size, 16)
+	return append(dst, '"')
+}
+
+// serveFile writes f as a 200, 206, 304, 412 or 416 response.
+// buffer is scratch space for the validators.
+func serveFile(resp *Response, r *Request, f *os.File, st os.FileInfo, contentType string, buffer *[]byte) error {
+	size := st.Size()
+	modtime := st.ModTime()
+
+	// ETag and Last-Modified share the scratch buffer
+	b := appendETag((*buffer)[:0], size, modtime)
+	etagLen := len(b)
+	b = AppendHTTPDate(b, modtime)
+	*buffer = b
+	etag, lastModified := b[:etagLen], b[etagLen:]
+
+	switch r.EvaluatePreconditions(etag, modtime) {
+	case PreconditionNotModified:
+		err := resp.WriteHeader(304)
+		if err != nil {
+			return err
+		}
+		writeValidators(resp, etag, lastModified)
+		return resp.EndHeader()
+	case PreconditionFailed:
+		return writeStatusText(resp, r, 412, nil)
+	}
+
+	ranges := GetByteRanges()
+	defer PutByteRanges(ranges)
+
+	var err error
+	*ranges, err = r.Ranges(size, etag, modtime, *ranges)
+	if err == ErrRangeNotSatisfiable {
+		return resp.WriteRangeNotSatisfiable(size)
+	}
+
+	// An invalid Range header is ignored
+	if err != nil || len(*ranges) == 0 {
+		resp.ContentLength = size
+		err = resp.WriteHeader(200)
+		if err != nil {
+			return err
+		}
+		resp.Write(contentTypeHeader)
+		resp.WriteString(contentType)
+		resp.Write(crlf)
+		resp.Write(acceptRangesBytesHeader)
+		writeValidators(resp, etag, lastModified)
+		err = resp.EndHeader()
+		if err != nil || r.Method == MethodHEAD {
+			return err
+		}
+		_, err = resp.SendFile(f, 0, size)
+		return err
+	}
+
+	err = resp.WritePartialHeader(*ranges, size, stringToBytes(contentType))
+	if err != nil {
+		return err
+	}
+	resp.Write(acceptRangesBytesHeader)
+	writeValidators(resp, etag, lastModified)
+	err = resp.EndHeader()
+	if err != nil {
+		return err
+	}
+	return resp.WriteRanges(f, *ranges, size, stringToBytes(contentType))
+}
+
+func writeValidators(resp *Response, etag, lastModified []byte) {
+	resp.Write(etagHeader)
+	resp.Write(etag)
+	resp.Write(crlf)
+	resp.Write(lastModifiedHeader)
+	resp.Write(lastModified)
+	resp.Write(crlf)
+}
@@ -0,0 +1,298 @@
+package h1
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFileServerTestRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"hello.txt":         "Hello, World!",
+		"digits.bin":        "0123456789",
+		"dir/index.html":    "<h1>index</h1>",
+		"empty/.keep":       "",
+		"static/app.css":    "body{}",
+		"../outside.txt":    "secret",
+		"dir/sub/data.json": "{}",
+	}
+	for name, data := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// readTestResponse parses the response written to request.
+func readTestResponse(t *testing.T, request, response string) *http.Response {
+	t.Helper()
+	method := request[:strings.IndexByte(request, ' ')]
+	res, err := http.ReadResponse(bufio.NewReader(strings.NewReader(response)), &http.Request{Method: method})
+	if err != nil {
+		t.Fatalf("invalid response %q: %v", response, err)
+	}
+	return res
+}
+
+func Test_FileServer(t *testing.T) {
+	root := newFileServerTestRoot(t)
+	fs := NewFileServer(root)
+
+	tests := []struct {
+		name        string
+		request     string
+		wantStatus  int
+		wantBody    string
+		wantType    string
+		wantHeaders map[string]string
+	}{
+		{"File", "GET /hello.txt HTTP/1.1\r\n\r\n", 200, "Hello, World!", "text/plain; charset=utf-8", map[string]string{"Accept-Ranges": "bytes"}},
+		{"HEAD", "HEAD /hello.txt HTTP/1.1\r\n\r\n", 200, "", "text/plain; charset=utf-8", map[string]string{"Content-Length": "13"}},
+		{"Escaped", "GET /hell%6F.txt HTTP/1.1\r\n\r\n", 200, "Hello, World!", "text/plain; charset=utf-8", nil},
+		{"Unknown Type", "GET /digits.bin HTTP/1.1\r\n\r\n", 200, "0123456789", "application/octet-stream", nil},
+		{"Index", "GET /dir/ HTTP/1.1\r\n\r\n", 200, "<h1>index</h1>", "text/html; charset=utf-8", nil},
+		{"Redirect", "GET /dir?x=1 HTTP/1.1\r\n\r\n", 301, "", "", map[string]string{"Location": "dir/?x=1"}},
+		{"Redirect Double Slash", "GET //dir HTTP/1.1\r\n\r\n", 301, "", "", map[string]string{"Location": "dir/"}},
+		{"Redirect Nested", "GET /dir/sub HTTP/1.1\r\n\r\n", 301, "", "", map[string]string{"Location": "sub/"}},
+		{"No Index", "GET /empty/ HTTP/1.1\r\n\r\n", 404, "404 Not Found", "text/plain; charset=utf-8", nil},
+		{"Not Found", "GET /missing.txt HTTP/1.1\r\n\r\n", 404, "404 Not Found", "text/plain; charset=utf-8", nil},
+		{"Dot Dot", "GET /../outside.txt HTTP/1.1\r\n\r\n", 404, "404 Not Found", "text/plain; charset=utf-8", nil},
+		{"Escaped Dot Dot", "GET /dir/%2e%2e/%2e%2e/outside.txt HTTP/1.1\r\n\r\n", 404, "404 Not Found", "text/plain; charset=utf-8", nil},
+		{"Clean", "GET /dir/sub/../../hello.txt HTTP/1.1\r\n\r\n", 200, "Hello, World!", "text/plain; charset=utf-8", nil},
+		{"NUL", "GET /hello.txt%00 HTTP/1.1\r\n\r\n", 400, "400 Bad Request", "text/plain; charset=utf-8", nil},
+		{"POST", "POST /hello.txt HTTP/1.1\r\nContent-Length: 0\r\n\r\n", 405, "405 Method Not Allowed", "text/plain; charset=utf-8", map[string]string{"Allow": "GET, HEAD"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := readTestResponse(t, tt.request, serveTestRequest(t, fs.Serve, tt.request))
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			body, _ := io.ReadAll(res.Body)
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if got := res.Header.Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			for k, v := range tt.wantHeaders {
+				if got := res.Header.Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func Test_FileServer_Prefix(t *testing.T) {
+	root := newFileServerTestRoot(t)
+	fs := NewFileServer(filepath.Join(root, "static"))
+	fs.Prefix = "/assets/"
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/assets/app.css", 200},
+		{"/assets/../assets/app.css", 200},
+		{"/assetsapp.css", 404},
+		{"/app.css", 404},
+		{"/assets/../hello.txt", 404},
+	}
+	for _, tt := range tests {
+		request := "GET " + tt.path + " HTTP/1.1\r\n\r\n"
+		res := readTestResponse(t, request, serveTestRequest(t, fs.Serve, request))
+		res.Body.Close()
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, res.StatusCode, tt.wantStatus)
+		}
+	}
+}
+
+func Test_FileServer_Symlinks(t *testing.T) {
+	root := newFileServerTestRoot(t)
+	outside := filepath.Dir(root)
+	links := map[string]string{
+		"inside.txt":         "hello.txt",
+		"outside.txt":        filepath.Join(outside, "outside.txt"),
+		"outside-dir":        outside,
+		"dir-link":           "dir",
+		"empty/index.html":   filepath.Join(outside, "outside.txt"),
+		"dir/sub/escape.txt": "../../../outside.txt",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Skip(err)
+		}
+	}
+	rootLink := filepath.Join(t.TempDir(), "root")
+	if err := os.Symlink(root, rootLink); err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		root       string
+		path       string
+		wantStatus int
+	}{
+		{root, "/inside.txt", 200},
+		{root, "/dir-link/", 200},
+		{root, "/outside.txt", 404},
+		{root, "/outside-dir/outside.txt", 404},
+		{root, "/empty/", 404},
+		{root, "/dir/sub/escape.txt", 404},
+		{rootLink, "/hello.txt", 200},
+		{rootLink, "/inside.txt", 200},
+		{rootLink, "/outside.txt", 404},
+	}
+	for _, tt := range tests {
+		request := "GET " + tt.path + " HTTP/1.1\r\n\r\n"
+		res := readTestResponse(t, request, serveTestRequest(t, NewFileServer(tt.root).Serve, request))
+		res.Body.Close()
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("GET %s from %s: status = %d, want %d", tt.path, tt.root, res.StatusCode, tt.wantStatus)
+		}
+	}
+}
+
+func Test_FileServer_Conditional(t *testing.T) {
+	root := newFileServerTestRoot(t)
+	fs := NewFileServer(root)
+
+	request := "GET /hello.txt HTTP/1.1\r\n\r\n"
+	res := readTestResponse(t, request, serveTestRequest(t, fs.Serve, request))
+	res.Body.Close()
+	etag := res.Header.Get("ETag")
+	lastModified := res.Header.Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("missing validators: ETag %q, Last-Modified %q", etag, lastModified)
+	}
+	modtime, err := time.Parse(http.TimeFormat, lastModified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		headers    string
+		wantStatus int
+	}{
+		{"ETag", "If-None-Match: " + etag + "\r\n", 304},
+		{"Weak ETag", "If-None-Match: W/" + etag + "\r\n", 304},
+		{"ETag List", "If-None-Match: \"a\", " + etag + "\r\n", 304},
+		{"Star", "If-None-Match: *\r\n", 304},
+		{"Other ETag", "If-None-Match: \"other\"\r\n", 200},
+		{"Modified Since", "If-Modified-Since: " + lastModified + "\r\n", 304},
+		{"Modified Later", "If-Modified-Since: " + modtime.Add(-time.Hour).Format(http.TimeFormat) + "\r\n", 200},
+		{"Invalid Date", "If-Modified-Since: yesterday\r\n", 200},
+		{"If-Match", "If-Match: " + etag + "\r\n", 200},
+		{"If-Match Mismatch", "If-Match: \"other\"\r\n", 412},
+		{"Unmodified Since", "If-Unmodified-Since: " + modtime.Add(-time.Hour).Format(http.TimeFormat) + "\r\n", 412},
+		{"ETag Precedence", "If-None-Match: \"other\"\r\nIf-Modified-Since: " + lastModified + "\r\n", 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := "GET /hello.txt HTTP/1.1\r\n" + tt.headers + "\r\n"
+			res := readTestResponse(t, request, serveTestRequest(t, fs.Serve, request))
+			defer res.Body.Close()
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == 304 && res.Header.Get("ETag") != etag {
+				t.Errorf("ETag = %q, want %q", res.Header.Get("ETag"), etag)
+			}
+		})
+	}
+}
+
+func Test_FileServer_Range(t *testing.T) {
+	root := newFileServerTestRoot(t)
+	fs := NewFileServer(root)
+
+	tests := []struct {
+		name        string
+		rangeHeader string
+		wantStatus  int
+		wantBody    string
+		wantRange   string
+		wantParts   []string
+		wantPartsCR []string
+	}{
+		{"Single", "bytes=2-4", 206, "234", "bytes 2-4/10", nil, nil},
+		{"Open Ended", "bytes=7-", 206, "789", "bytes 7-9/10", nil, nil},
+		{"Suffix", "bytes=-3", 206, "789", "bytes 7-9/10", nil, nil},
+		{"Clamped", "bytes=8-100", 206, "89", "bytes 8-9/10", nil, nil},
+		{"Whole Suffix", "bytes=-100", 206, "0123456789", "bytes 0-9/10", nil, nil},
+		{"Multiple", "bytes=0-1, 5-6,-1", 206, "", "", []string{"01", "56", "9"}, []string{"bytes 0-1/10", "bytes 5-6/10", "bytes 9-9/10"}},
+		{"Skip Unsatisfiable", "bytes=20-30,1-1", 206, "1", "bytes 1-1/10", nil, nil},
+		{"Not Satisfiable", "bytes=10-", 416, "", "bytes */10", nil, nil},
+		{"Zero Suffix", "bytes=-0", 416, "", "bytes */10", nil, nil},
+		{"Invalid", "bytes=5-2", 200, "0123456789", "", nil, nil},
+		{"Other Unit", "items=0-1", 200, "0123456789", "", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := "GET /digits.bin HTTP/1.1\r\nRange: " + tt.rangeHeader + "\r\n\r\n"
+			res := readTestResponse(t, request, serveTestRequest(t, fs.Serve, request))
+			defer res.Body.Close()
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if got := res.Header.Get("Content-Range"); got != tt.wantRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.wantRange)
+			}
+
+			if tt.wantParts == nil {
+				body, _ := io.ReadAll(res.Body)
+				if string(body) != tt.wantBody {
+					t.Errorf("body = %q, want %q", body, tt.wantBody)
+				}
+				return
+			}
+
+			mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+			if err != nil || mediaType != "multipart/byteranges" {
+				t.Fatalf("Content-Type = %q", res.Header.Get("Content-Type"))
+			}
+			body, _ := io.ReadAll(res.Body)
+			if int64(len(body)) != res.ContentLength {
+				t.Errorf("Content-Length = %d, body is %d bytes", res.ContentLength, len(body))
+			}
+			mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+			for i := range tt.wantParts {
+				part, err := mr.NextPart()
+				if err != nil {
+					t.Fatalf("part %d: %v", i, err)
+				}
+				data, _ := io.ReadAll(part)
+				if string(data) != tt.wantParts[i] {
+					t.Errorf("part %d = %q, want %q", i, data, tt.wantParts[i])
+				}
+				if got := part.Header.Get("Content-Range"); got != tt.wantPartsCR[i] {
+					t.Errorf("part %d Content-Range = %q, want %q", i, got, tt.wantPartsCR[i])
+				}
+			}
+			if _, err := mr.NextPart(); err != io.EOF {
+				t.Errorf("NextPart() error = %v, want EOF", err)
+			}
+		})
+	}
+}
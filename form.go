@@ -0,0 +1,127 @@
+package h1
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// DefaultMaxFormSize is the body size limit used by ParseForm when RequestReader.MaxFormSize is zero.
+const DefaultMaxFormSize = 1 << 20
+
+var ErrFormTooLarge = errors.New("form body too large")
+
+var ContentTypeHeader = []byte("Content-Type")
+var formURLEncoded = []byte("application/x-www-form-urlencoded")
+
+func isFormURLEncoded(contentType []byte) bool {
+	// Ignore parameters such as "; charset=utf-8"
+	if semiIndex := indexByte(contentType, ';'); semiIndex != -1 {
+		contentType = trimLWS(contentType[:semiIndex])
+	}
+	return stricmp(contentType, formURLEncoded)
+}
+
+// ParseForm reads an application/x-www-form-urlencoded request body into a pooled buffer and decodes it in place.
+// The body is only read for POST, PUT and PATCH requests with a form Content-Type, otherwise ParseForm does nothing.
+// The body must not have been read yet. ErrFormTooLarge is returned if the body is larger than MaxFormSize.
+func (r *RequestReader) ParseForm() error {
+	req := &r.Request
+	if req.isFormParsed {
+		return nil
+	}
+	req.isFormParsed = true
+
+	switch req.Method {
+	case MethodPOST, MethodPUT, MethodPATCH:
+	default:
+		return nil
+	}
+	h, ok := req.GetHeader(ContentTypeHeader)
+	if !ok || !isFormURLEncoded(h.RawValue) {
+		return nil
+	}
+
+	maxSize := r.MaxFormSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxFormSize
+	}
+	if req.ContentLength < 0 {
+		return ErrInvalidContentLength
+	}
+	if req.ContentLength > maxSize {
+		return ErrFormTooLarge
+	}
+
+	req.formBuffer = GetBuffer()
+	buf := *req.formBuffer
+	if int64(cap(buf)) < req.ContentLength {
+		// The larger buffer is not pooled, see resetForm
+		PutBuffer(req.formBuffer)
+		buf = make([]byte, req.ContentLength)
+		req.formBuffer = &buf
+	}
+	buf = buf[:req.ContentLength]
+
+	body := r.Body()
+	_, err := io.ReadFull(body, buf)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	req.postForm, err = ParseRawQueryOptions(buf, req.postForm, req.URI.QueryOptions)
+	return err
+}
+
+// PostForm returns the arguments of the request body parsed by ParseForm.
+func (r *Request) PostForm() []Query {
+	return r.postForm
+}
+
+// Form returns the arguments of the request body (if parsed by ParseForm) followed by the URI query arguments.
+func (r *Request) Form() []Query {
+	if !r.isFormMerged {
+		r.form = append(r.form[:0], r.postForm...)
+		r.form = append(r.form, r.URI.Query()...)
+		r.isFormMerged = r.isFormParsed
+	}
+
+	return r.form
+}
+
+// FormValue returns the first value for key, with body arguments taking precedence over URI query arguments.
+func (r *Request) FormValue(key []byte) ([]byte, error) {
+	for _, q := range r.Form() {
+		if bytes.Equal(q.Key, key) {
+			return q.Value, nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// PostFormValue returns the first value for key in the request body.
+func (r *Request) PostFormValue(key []byte) ([]byte, error) {
+	for _, q := range r.postForm {
+		if bytes.Equal(q.Key, key) {
+			return q.Value, nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+func (r *Request) resetForm() {
+	if r.formBuffer != nil {
+		// Buffers grown to a large form are left to the GC instead of filling the shared pool
+		if cap(*r.formBuffer) <= BufferPoolSize {
+			PutBuffer(r.formBuffer)
+		}
+		r.formBuffer = nil
+	}
+	r.isFormParsed = false
+	r.isFormMerged = false
+	r.postForm = r.postForm[:0]
+	r.form = r.form[:0]
+}
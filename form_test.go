@@ -0,0 +1,178 @@
+package h1
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newTestRequestReader(data string) *RequestReader {
+	return &RequestReader{
+		R:          strings.NewReader(data),
+		ReadBuffer: make([]byte, 8192),
+	}
+}
+
+func Test_RequestReader_ParseForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  string
+		maxSize  int64
+		wantPost [][2]string
+		wantForm [][2]string
+		wantErr  error
+	}{
+		{
+			"post form",
+			"POST /submit?a=query&q=1 HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 26\r\n\r\na=body&name=John+Doe&empty",
+			0,
+			[][2]string{{"a", "body"}, {"name", "John Doe"}, {"empty", ""}},
+			[][2]string{{"a", "body"}, {"name", "John Doe"}, {"empty", ""}, {"a", "query"}, {"q", "1"}},
+			nil,
+		},
+		{
+			"charset parameter",
+			"PUT / HTTP/1.1\r\nContent-Type: Application/X-WWW-Form-Urlencoded; charset=utf-8\r\nContent-Length: 11\r\n\r\nx=%E2%9C%A8",
+			0,
+			[][2]string{{"x", "✨"}},
+			[][2]string{{"x", "✨"}},
+			nil,
+		},
+		{
+			"get ignores body",
+			"GET /?a=1 HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 3\r\n\r\nb=2",
+			0,
+			nil,
+			[][2]string{{"a", "1"}},
+			nil,
+		},
+		{
+			"other content type",
+			"POST /?a=1 HTTP/1.1\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}",
+			0,
+			nil,
+			[][2]string{{"a", "1"}},
+			nil,
+		},
+		{
+			"too large",
+			"POST / HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 11\r\n\r\na=123456789",
+			10,
+			nil,
+			nil,
+			ErrFormTooLarge,
+		},
+		{
+			"truncated body",
+			"POST / HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 100\r\n\r\na=1",
+			0,
+			nil,
+			nil,
+			errAny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRequestReader(tt.request)
+			r.MaxFormSize = tt.maxSize
+			if _, err := r.Next(); err != nil {
+				t.Fatal(err)
+			}
+
+			err := r.ParseForm()
+			if tt.wantErr == errAny {
+				if err == nil {
+					t.Fatal("ParseForm() error = nil, want error")
+				}
+				return
+			}
+			if err != tt.wantErr {
+				t.Fatalf("ParseForm() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			compareQuery(t, "PostForm()", r.Request.PostForm(), tt.wantPost)
+			compareQuery(t, "Form()", r.Request.Form(), tt.wantForm)
+		})
+	}
+}
+
+func Test_Request_FormValue(t *testing.T) {
+	r := newTestRequestReader("POST /?a=query&q=1 HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 10\r\n\r\na=body&b=2")
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := r.Request.FormValue([]byte("a")); err != nil || !bytes.Equal(v, []byte("body")) {
+		t.Errorf("FormValue(a) = %q, %v, want %q", v, err, "body")
+	}
+	if v, err := r.Request.FormValue([]byte("q")); err != nil || !bytes.Equal(v, []byte("1")) {
+		t.Errorf("FormValue(q) = %q, %v, want %q", v, err, "1")
+	}
+	if _, err := r.Request.PostFormValue([]byte("q")); err != ErrKeyNotFound {
+		t.Errorf("PostFormValue(q) error = %v, want %v", err, ErrKeyNotFound)
+	}
+	if v, err := r.Request.PostFormValue([]byte("b")); err != nil || !bytes.Equal(v, []byte("2")) {
+		t.Errorf("PostFormValue(b) = %q, %v, want %q", v, err, "2")
+	}
+
+	r.Request.Reset()
+	if len(r.Request.PostForm()) != 0 || r.Request.formBuffer != nil {
+		t.Error("Reset() did not release the form")
+	}
+}
+
+func Test_RequestReader_ParseForm_NegativeLength(t *testing.T) {
+	r := newTestRequestReader("POST / HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\n\r\n")
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	r.Request.ContentLength = -1
+	if err := r.ParseForm(); err != ErrInvalidContentLength {
+		t.Errorf("ParseForm() error = %v, want %v", err, ErrInvalidContentLength)
+	}
+}
+
+func Test_RequestReader_ParseForm_LargeBuffer(t *testing.T) {
+	body := "a=" + strings.Repeat("x", 2*BufferPoolSize)
+	r := newTestRequestReader("POST / HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: " +
+		strconv.Itoa(len(body)) + "\r\n\r\n" + body)
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.Request.PostFormValue([]byte("a")); err != nil || len(v) != 2*BufferPoolSize {
+		t.Fatalf("PostFormValue(a) = %d bytes, %v", len(v), err)
+	}
+
+	formBuffer := r.Request.formBuffer
+	r.Request.Reset()
+	for i := 0; i < 100; i++ {
+		if GetBuffer() == formBuffer {
+			t.Fatalf("GetBuffer() returned the %d byte form buffer", cap(*formBuffer))
+		}
+	}
+}
+
+var errAny = &struct{ error }{}
+
+func compareQuery(t *testing.T, name string, got []Query, want [][2]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s got %d args, want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		if string(got[i].Key) != want[i][0] || string(got[i].Value) != want[i][1] {
+			t.Errorf("%s arg %d = %q=%q, want %q=%q", name, i, got[i].Key, got[i].Value, want[i][0], want[i][1])
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package h1
+
+// Handler writes the response to the current request of req.
+// Returning an error closes the connection.
+type Handler func(resp *Response, req *RequestReader) error
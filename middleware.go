@@ -0,0 +1,264 @@
+package h1
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Middleware wraps a Handler with code running around it.
+type Middleware func(next Handler) Handler
+
+// Chain is an immutable list of middleware. The first middleware is the outermost.
+type Chain struct {
+	middlewares []Middleware
+}
+
+// NewChain returns a Chain of middlewares.
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain{middlewares: append([]Middleware(nil), middlewares...)}
+}
+
+// Append returns a new Chain running middlewares inside those of c.
+func (c Chain) Append(middlewares ...Middleware) Chain {
+	m := make([]Middleware, 0, len(c.middlewares)+len(middlewares))
+	m = append(m, c.middlewares...)
+	return Chain{middlewares: append(m, middlewares...)}
+}
+
+// Then wraps h with the middlewares of c.
+func (c Chain) Then(h Handler) Handler {
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	return h
+}
+
+var connectionCloseHeader = []byte("Connection: close\r\n")
+
+// PanicError is returned by Recover when the handler panics.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panic: %v", e.Value)
+}
+
+// Recover turns a panic of the handler into a *PanicError.
+// If no part of the response has reached the connection yet, the buffered output is discarded
+// and 500 Internal Server Error is written instead. The error makes the Server close the connection.
+func Recover(next Handler) Handler {
+	return func(resp *Response, req *RequestReader) (err error) {
+		n, sent := resp.n, resp.sent
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if pe, ok := v.(*PanicError); ok {
+				// Raised again by Timeout, with the stack of the handler goroutine
+				err = pe
+			} else {
+				stack := make([]byte, 4096)
+				stack = stack[:runtime.Stack(stack, false)]
+				err = &PanicError{Value: v, Stack: stack}
+			}
+
+			if resp.sent != sent {
+				return
+			}
+			resp.n = n
+			resp.Chunked = false
+			resp.discardBody = false
+			writeStatusText(resp, &req.Request, 500, connectionCloseHeader)
+		}()
+		return next(resp, req)
+	}
+}
+
+var RequestIDHeader = []byte("X-Request-Id")
+
+// MaxRequestIDLen is the length limit of a X-Request-Id sent by the client.
+const MaxRequestIDLen = 128
+
+var requestIDPrefix = func() []byte {
+	var b [8]byte
+	rand.Read(b[:])
+	return append([]byte(hex.EncodeToString(b[:])), '-')
+}()
+
+var requestIDCounter uint64
+
+// RequestID makes sure the request has an X-Request-Id header and adds it to the response.
+// A missing or invalid ID is replaced by one unique to the process: a random prefix followed by a counter.
+// The generated value is valid until the end of the request.
+func RequestID(next Handler) Handler {
+	return func(resp *Response, req *RequestReader) error {
+		r := &req.Request
+		h, ok := r.GetHeader(RequestIDHeader)
+		if ok {
+			if id := trimLWS(h.RawValue); validRequestID(id) {
+				resp.AddHeader(RequestIDHeader, id)
+				return next(resp, req)
+			}
+		}
+
+		// The generated ID lives in the header lines of the response
+		start := len(resp.extraHeaders) + len(RequestIDHeader) + len(": ")
+		resp.extraHeaders = append(resp.extraHeaders, RequestIDHeader...)
+		resp.extraHeaders = append(resp.extraHeaders, ": "...)
+		resp.extraHeaders = append(resp.extraHeaders, requestIDPrefix...)
+		resp.extraHeaders = strconv.AppendUint(resp.extraHeaders, atomic.AddUint64(&requestIDCounter, 1), 16)
+		id := resp.extraHeaders[start:len(resp.extraHeaders):len(resp.extraHeaders)]
+		resp.extraHeaders = append(resp.extraHeaders, "\r\n"...)
+
+		if ok {
+			h.RawValue = id
+		} else {
+			r.Headers = append(r.Headers, Header{Name: RequestIDHeader, RawValue: id})
+		}
+		return next(resp, req)
+	}
+}
+
+// validRequestID reports whether id is a non-empty run of visible ASCII characters not longer than MaxRequestIDLen.
+func validRequestID(id []byte) bool {
+	if len(id) == 0 || len(id) > MaxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+var ErrHandlerTimeout = errors.New("handler timeout")
+
+type timeoutResponse struct {
+	buf  bytes.Buffer
+	resp *Response
+}
+
+var timeoutResponsePool = sync.Pool{
+	New: func() any {
+		tr := &timeoutResponse{}
+		tr.resp = GetResponse(&tr.buf)
+		return tr
+	},
+}
+
+type timeoutResult struct {
+	err   error
+	panic *PanicError
+}
+
+// Timeout answers 503 Service Unavailable if the handler does not return within d.
+//
+// The handler runs in its own goroutine and writes to a buffered response, which is copied to resp
+// if it returns in time. After a timeout the 503 is flushed and the connection is closed,
+// so that the handler fails on its next read of the request body; its output is dropped.
+// Timeout waits for the handler to return, then returns ErrHandlerTimeout. The wait is not bounded:
+// until a handler blocked on something other than the connection returns, the connection's goroutine,
+// Response and buffers stay in use.
+// A panic of the handler is raised again as a *PanicError with the stack of the handler.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(resp *Response, req *RequestReader) error {
+			tr := timeoutResponsePool.Get().(*timeoutResponse)
+			tmp := tr.resp
+			tmp.ContentLength = resp.ContentLength
+			tmp.Chunked = resp.Chunked
+			tmp.DateServerHeaderFunc = resp.DateServerHeaderFunc
+			tmp.OmitDateServerHeader = resp.OmitDateServerHeader
+			tmp.NoBody = resp.NoBody
+			tmp.extraHeaders = append(tmp.extraHeaders[:0], resp.extraHeaders...)
+
+			done := make(chan timeoutResult, 1)
+			go func() {
+				defer func() {
+					if v := recover(); v != nil {
+						done <- timeoutResult{panic: &PanicError{Value: v, Stack: debug.Stack()}}
+					}
+				}()
+				done <- timeoutResult{err: next(tmp, req)}
+			}()
+
+			timer := time.NewTimer(d)
+			select {
+			case result := <-done:
+				timer.Stop()
+				tmp.Flush()
+				_, err := resp.Write(tr.buf.Bytes())
+				putTimeoutResponse(tr)
+
+				if result.panic != nil {
+					panic(result.panic)
+				}
+				if result.err != nil {
+					return result.err
+				}
+				return err
+
+			case <-timer.C:
+				resp.Chunked = false
+				resp.discardBody = false
+				writeStatusText(resp, &req.Request, 503, connectionCloseHeader)
+				resp.Flush()
+
+				// req and the connection must not be used by the handler once Timeout returns.
+				// A connection of the Netpoll event loop is closed through it, which owns its descriptor.
+				if sc := req.conn; sc != nil && sc.close != nil {
+					sc.close()
+				} else if c, ok := req.R.(io.Closer); ok {
+					c.Close()
+				}
+				result := <-done
+				putTimeoutResponse(tr)
+
+				if result.panic != nil {
+					panic(result.panic)
+				}
+				return ErrHandlerTimeout
+			}
+		}
+	}
+}
+
+func putTimeoutResponse(tr *timeoutResponse) {
+	tr.resp.Reset()
+	tr.resp.DateServerHeaderFunc = nil
+	tr.buf.Reset()
+	timeoutResponsePool.Put(tr)
+}
+
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// BodyLimit answers 413 Request Entity Too Large to requests with a body larger than n bytes without calling the handler.
+// ErrBodyTooLarge is returned, so the Server closes the connection instead of reading the body.
+func BodyLimit(n int64) Middleware {
+	return func(next Handler) Handler {
+		return func(resp *Response, req *RequestReader) error {
+			if req.Request.ContentLength > n {
+				err := writeStatusText(resp, &req.Request, 413, connectionCloseHeader)
+				if err != nil {
+					return err
+				}
+				return ErrBodyTooLarge
+			}
+			return next(resp, req)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package h1
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Chain(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(resp *Response, req *RequestReader) error {
+				order = append(order, name)
+				return next(resp, req)
+			}
+		}
+	}
+
+	base := NewChain(trace("a"), trace("b"))
+	extended := base.Append(trace("c"))
+	base.Append(trace("x")) // must not change extended
+
+	h := extended.Then(func(resp *Response, req *RequestReader) error {
+		order = append(order, "handler")
+		return nil
+	})
+	h(nil, nil)
+
+	if got := strings.Join(order, ","); got != "a,b,c,handler" {
+		t.Errorf("order = %q, want %q", got, "a,b,c,handler")
+	}
+}
+
+func Test_Middleware(t *testing.T) {
+	panicHandler := func(resp *Response, req *RequestReader) error {
+		resp.ContentLength = 4
+		resp.WriteHeader(200)
+		resp.EndHeader()
+		resp.WriteString("half")
+		panic("boom")
+	}
+	slowHandler := func(resp *Response, req *RequestReader) error {
+		time.Sleep(200 * time.Millisecond)
+		return helloHandler(resp, req)
+	}
+	echoIDHandler := func(resp *Response, req *RequestReader) error {
+		h, _ := req.Request.GetHeader(RequestIDHeader)
+		resp.ContentLength = int64(len(h.RawValue))
+		resp.WriteHeader(200)
+		resp.EndHeader()
+		_, err := resp.Write(h.RawValue)
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		handler  Handler
+		requests string
+		want     string
+	}{
+		{
+			"Recover",
+			NewChain(Recover).Then(panicHandler),
+			"GET /a HTTP/1.1\r\n\r\nGET /b HTTP/1.1\r\n\r\n",
+			"HTTP/1.1 500 Internal Server Error\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 25\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\n\r\n500 Internal Server Error",
+		},
+		{
+			"Request ID",
+			NewChain(RequestID).Then(echoIDHandler),
+			"GET / HTTP/1.1\r\nX-Request-Id: abc-123\r\nConnection: close\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nX-Request-Id: abc-123\r\nContent-Length: 7\r\n\r\nabc-123",
+		},
+		{
+			"Timeout",
+			NewChain(Timeout(50 * time.Millisecond)).Then(slowHandler),
+			"GET /slow HTTP/1.1\r\n\r\nGET /b HTTP/1.1\r\n\r\n",
+			"HTTP/1.1 503 Service Unavailable\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 23\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\n\r\n503 Service Unavailable",
+		},
+		{
+			"In Time",
+			NewChain(RequestID, Timeout(time.Second)).Then(helloHandler),
+			"GET /fast HTTP/1.1\r\nX-Request-Id: r1\r\nConnection: close\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nX-Request-Id: r1\r\nContent-Length: 5\r\n\r\n/fast",
+		},
+		{
+			"Body Limit",
+			NewChain(BodyLimit(4)).Then(helloHandler),
+			"POST /a HTTP/1.1\r\nContent-Length: 4\r\n\r\nbodyPOST /b HTTP/1.1\r\nContent-Length: 5\r\n\r\nbody!",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a" +
+				"HTTP/1.1 413 Request Entity Too Large\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 28\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\n\r\n413 Request Entity Too Large",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fds := NewTestFastDateServer("h1", FixedClock(testDate))
+			s := &Server{Handler: tt.handler, DateServerHeaderFunc: fds.GetDate}
+			out, _ := serveTestConn(s, tt.requests)
+			if out != tt.want {
+				t.Errorf("ServeConn() wrote\n%q\nwant\n%q", out, tt.want)
+			}
+		})
+	}
+}
+
+func Test_RequestID_Generated(t *testing.T) {
+	var ids []string
+	h := RequestID(func(resp *Response, req *RequestReader) error {
+		h, ok := req.Request.GetHeader(RequestIDHeader)
+		if !ok {
+			t.Fatal("no X-Request-Id header")
+		}
+		ids = append(ids, string(h.RawValue))
+		return helloHandler(resp, req)
+	})
+
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	s := &Server{Handler: h, DateServerHeaderFunc: fds.GetDate}
+	out, err := serveTestConn(s, "GET /a HTTP/1.1\r\n\r\nGET /b HTTP/1.1\r\nX-Request-Id: bad id\r\nConnection: close\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 2 || ids[0] == ids[1] || !strings.HasPrefix(ids[0], string(requestIDPrefix)) {
+		t.Fatalf("ids = %q", ids)
+	}
+	for _, id := range ids {
+		if !strings.Contains(out, "X-Request-Id: "+id+"\r\n") {
+			t.Errorf("response %q does not contain id %q", out, id)
+		}
+	}
+}
+
+func Test_Timeout_WaitsForHandler(t *testing.T) {
+	var exited int32
+	var bodyErr error
+	h := Timeout(20 * time.Millisecond)(func(resp *Response, req *RequestReader) error {
+		defer atomic.StoreInt32(&exited, 1)
+		time.Sleep(50 * time.Millisecond)
+		// The rest of the body never arrives, the read fails once the connection is closed
+		_, bodyErr = io.ReadAll(req.Body())
+		return helloHandler(resp, req)
+	})
+
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	s := &Server{Handler: h, DateServerHeaderFunc: fds.GetDate}
+	out, err := serveTestConn(s, "POST /a HTTP/1.1\r\nContent-Length: 10\r\n\r\nab")
+	if err != ErrHandlerTimeout {
+		t.Errorf("ServeConn() error = %v, want %v", err, ErrHandlerTimeout)
+	}
+	if !strings.HasPrefix(out, "HTTP/1.1 503 Service Unavailable\r\n") {
+		t.Errorf("ServeConn() wrote %q", out)
+	}
+	if atomic.LoadInt32(&exited) == 0 {
+		t.Error("ServeConn() returned before the handler")
+	}
+	if bodyErr == nil {
+		t.Error("body read after the timeout succeeded")
+	}
+}
+
+func Test_Timeout_Panic(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{"In Time", 0},
+		{"After Timeout", 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewChain(Recover, Timeout(20*time.Millisecond)).Then(func(resp *Response, req *RequestReader) error {
+				time.Sleep(tt.delay)
+				panic("boom")
+			})
+
+			s := &Server{Handler: h}
+			_, err := serveTestConn(s, "GET /a HTTP/1.1\r\n\r\n")
+			pe, ok := err.(*PanicError)
+			if !ok || pe.Value != "boom" {
+				t.Fatalf("ServeConn() error = %v, want a *PanicError", err)
+			}
+			// The stack is the one of the handler goroutine
+			if !strings.Contains(string(pe.Stack), "Test_Timeout_Panic") {
+				t.Errorf("stack does not contain the handler:\n%s", pe.Stack)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package h1
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultMultipartMaxParts is the part limit used when MultipartReader.MaxParts is zero.
+	DefaultMultipartMaxParts = 1000
+	// DefaultMultipartMaxHeaderSize is the per-part header size limit used when MultipartReader.MaxHeaderSize is zero.
+	DefaultMultipartMaxHeaderSize = 2048
+	// DefaultMultipartMaxSize is the total size limit used when MultipartReader.MaxSize is zero.
+	DefaultMultipartMaxSize = 32 << 20
+)
+
+var ErrNotMultipart = errors.New("request is not multipart")
+var ErrMultipartBoundary = errors.New("invalid multipart boundary")
+var ErrMultipartMalformed = errors.New("malformed multipart body")
+var ErrMultipartTooManyParts = errors.New("too many multipart parts")
+var ErrMultipartHeaderTooLarge = errors.New("multipart part header too large")
+var ErrMultipartTooLarge = errors.New("multipart body too large")
+
+// MultipartReader is a streaming multipart/form-data (RFC 7578) reader.
+//
+// The body is read through a single pooled buffer. The headers of the current part are kept at the start
+// of the buffer so that Part.Headers can point into it, and the part body streams through the rest.
+type MultipartReader struct {
+	R io.Reader
+
+	// Limits (defaults are used if zero)
+	MaxParts      int
+	MaxHeaderSize int
+	MaxSize       int64
+
+	delimiter []byte // "\r\n--" + boundary
+
+	buffer *[]byte
+	buf    []byte
+
+	hdrEnd int // end of the current part's headers in buf
+	start  int // start of the unread bytes in buf
+	end    int // end of the unread bytes in buf
+
+	partDone bool // the current part has reached the delimiter
+	done     bool // the close delimiter has been read
+	eof      bool // R returned io.EOF
+
+	parts int
+	total int64
+
+	part Part
+}
+
+// Part is a single part of a multipart body.
+// Headers point into the reader's buffer and are only valid until the next call to NextPart.
+type Part struct {
+	Headers []Header
+
+	mr *MultipartReader
+}
+
+var multipartReaderPool = sync.Pool{
+	New: func() any {
+		return &MultipartReader{}
+	},
+}
+
+// NewMultipartReader returns a pooled MultipartReader reading from r with the given boundary.
+// Close must be called to return the reader and its buffer to the pool.
+func NewMultipartReader(r io.Reader, boundary []byte) (*MultipartReader, error) {
+	if len(boundary) == 0 || len(boundary) > 70 {
+		return nil, ErrMultipartBoundary
+	}
+
+	mr := multipartReaderPool.Get().(*MultipartReader)
+	mr.R = r
+	mr.delimiter = append(append(mr.delimiter[:0], "\r\n--"...), boundary...)
+	mr.buffer = GetBuffer()
+	mr.buf = (*mr.buffer)[:cap(*mr.buffer)]
+
+	// Pretend the body starts with a CRLF so the first boundary is found like any other delimiter
+	copy(mr.buf, crlf)
+	mr.end = len(crlf)
+
+	mr.part.mr = mr
+	return mr, nil
+}
+
+var multipartPrefix = []byte("multipart/")
+var boundaryParam = []byte("boundary")
+
+// MultipartReader returns a MultipartReader for a multipart request body.
+// The body must not have been read yet.
+func (r *RequestReader) MultipartReader() (*MultipartReader, error) {
+	h, ok := r.Request.GetHeader(ContentTypeHeader)
+	if !ok || len(h.RawValue) < len(multipartPrefix) || !stricmp(h.RawValue[:len(multipartPrefix)], multipartPrefix) {
+		return nil, ErrNotMultipart
+	}
+
+	boundary, ok := HeaderParam(h.RawValue, boundaryParam)
+	if !ok {
+		return nil, ErrMultipartBoundary
+	}
+
+	return NewMultipartReader(r.Body(), boundary)
+}
+
+func (mr *MultipartReader) maxHeaderSize() int {
+	max := mr.MaxHeaderSize
+	if max == 0 {
+		max = DefaultMultipartMaxHeaderSize
+	}
+	// Leave room for the body window
+	if limit := len(mr.buf) - 2*len(mr.delimiter) - 8; max > limit {
+		max = limit
+	}
+	return max
+}
+
+// fill moves the unread bytes right after the current headers and reads more bytes from R.
+func (mr *MultipartReader) fill() error {
+	if mr.eof {
+		return io.ErrUnexpectedEOF
+	}
+
+	if mr.start > mr.hdrEnd {
+		mr.end = mr.hdrEnd + copy(mr.buf[mr.hdrEnd:], mr.buf[mr.start:mr.end])
+		mr.start = mr.hdrEnd
+	}
+
+	n, err := mr.R.Read(mr.buf[mr.end:])
+	mr.end += n
+	mr.total += int64(n)
+
+	maxSize := mr.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMultipartMaxSize
+	}
+	if maxSize > 0 && mr.total > maxSize {
+		return ErrMultipartTooLarge
+	}
+
+	if err == io.EOF {
+		mr.eof = true
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	return err
+}
+
+// partialDelimiter returns the length of the longest suffix of window that is a prefix of the delimiter.
+// Those bytes can not be returned as body data until more bytes are read.
+func (mr *MultipartReader) partialDelimiter(window []byte) int {
+	i := len(window) - len(mr.delimiter) + 1
+	if i < 0 {
+		i = 0
+	}
+	for ; i < len(window); i++ {
+		if window[i] == '\r' && bytes.HasPrefix(mr.delimiter, window[i:]) {
+			return len(window) - i
+		}
+	}
+	return 0
+}
+
+// body returns up to max bytes of the current part body and consumes them.
+func (mr *MultipartReader) body(max int) ([]byte, error) {
+	for {
+		if mr.partDone {
+			return nil, io.EOF
+		}
+
+		// Rolling search: look for the delimiter in the window, keeping a possible partial delimiter at the end
+		window := mr.buf[mr.start:mr.end]
+		avail := len(window)
+		if idx := bytes.Index(window, mr.delimiter); idx != -1 {
+			if idx == 0 {
+				mr.partDone = true
+				return nil, io.EOF
+			}
+			avail = idx
+		} else {
+			avail -= mr.partialDelimiter(window)
+		}
+
+		if avail > 0 {
+			if avail > max {
+				avail = max
+			}
+			mr.start += avail
+			return window[:avail], nil
+		}
+
+		err := mr.fill()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ensure makes sure at least n unread bytes are buffered.
+func (mr *MultipartReader) ensure(n int) error {
+	for mr.end-mr.start < n {
+		err := mr.fill()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NextPart skips the rest of the current part and returns the next one.
+// io.EOF is returned after the last part.
+func (mr *MultipartReader) NextPart() (*Part, error) {
+	if mr.done {
+		return nil, io.EOF
+	}
+
+	// Discard the rest of the current part (or the preamble)
+	for {
+		_, err := mr.body(len(mr.buf))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The previous headers are no longer needed
+	mr.hdrEnd = 0
+	mr.part.Headers = mr.part.Headers[:0]
+
+	// Skip the delimiter and check for the close delimiter "--"
+	err := mr.ensure(len(mr.delimiter) + 2)
+	if err != nil {
+		return nil, err
+	}
+	mr.start += len(mr.delimiter)
+	if mr.buf[mr.start] == '-' && mr.buf[mr.start+1] == '-' {
+		mr.done = true
+		return nil, io.EOF
+	}
+
+	// Skip transport padding up to the CRLF
+	for {
+		if mr.start == mr.end {
+			err = mr.fill()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		c := mr.buf[mr.start]
+		if c != ' ' && c != '\t' {
+			break
+		}
+		mr.start++
+	}
+
+	mr.parts++
+	maxParts := mr.MaxParts
+	if maxParts == 0 {
+		maxParts = DefaultMultipartMaxParts
+	}
+	if maxParts > 0 && mr.parts > maxParts {
+		return nil, ErrMultipartTooManyParts
+	}
+
+	err = mr.parseHeaders()
+	if err != nil {
+		return nil, err
+	}
+	mr.partDone = false
+
+	return &mr.part, nil
+}
+
+// parseHeaders parses the part headers (starting with the CRLF after the delimiter) at the start of buf.
+func (mr *MultipartReader) parseHeaders() error {
+	// Move the headers to the start of the buffer
+	mr.end = copy(mr.buf, mr.buf[mr.start:mr.end])
+	mr.start = 0
+
+	maxHeaderSize := mr.maxHeaderSize()
+
+parse:
+	mr.part.Headers = mr.part.Headers[:0]
+
+	line, next, err := splitLine(mr.buf[:mr.end])
+	if err == nil && len(line) != 0 {
+		// Missing CRLF after the delimiter
+		return ErrMultipartMalformed
+	}
+	for err == nil {
+		line, next, err = splitLine(next)
+		if err != nil {
+			break
+		}
+		if len(line) == 0 {
+			// End of headers
+			mr.hdrEnd = mr.end - len(next)
+			if mr.hdrEnd > maxHeaderSize {
+				return ErrMultipartHeaderTooLarge
+			}
+			mr.start = mr.hdrEnd
+			return nil
+		}
+		h := Header{}
+		h.Name, h.RawValue = ParseHeaderLine(line)
+		if h.RawValue == nil {
+			return ErrMultipartMalformed
+		}
+		mr.part.Headers = append(mr.part.Headers, h)
+	}
+
+	if err != ErrBufferTooSmall {
+		return err
+	}
+	if mr.end >= maxHeaderSize {
+		return ErrMultipartHeaderTooLarge
+	}
+	err = mr.fill()
+	if err != nil {
+		return err
+	}
+	goto parse
+}
+
+// Close returns the reader and its buffer to the pool. If R is a BodyReader it is closed too.
+// The reader and its parts must not be used after Close.
+func (mr *MultipartReader) Close() error {
+	var err error
+	if br, ok := mr.R.(*BodyReader); ok {
+		err = br.Close()
+	}
+
+	PutBuffer(mr.buffer)
+	mr.buffer = nil
+	mr.buf = nil
+	mr.R = nil
+	mr.MaxParts = 0
+	mr.MaxHeaderSize = 0
+	mr.MaxSize = 0
+	mr.hdrEnd, mr.start, mr.end = 0, 0, 0
+	mr.partDone, mr.done, mr.eof = false, false, false
+	mr.parts = 0
+	mr.total = 0
+	mr.part.Headers = mr.part.Headers[:0]
+
+	multipartReaderPool.Put(mr)
+	return err
+}
+
+// Read reads the body of the part. io.EOF is returned at the end of the part.
+func (p *Part) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	data, err := p.mr.body(len(b))
+	return copy(b, data), err
+}
+
+// GetHeader returns the first part header named name.
+func (p *Part) GetHeader(name []byte) (*Header, bool) {
+	for i := range p.Headers {
+		if stricmp(p.Headers[i].Name, name) {
+			return &p.Headers[i], true
+		}
+	}
+	return nil, false
+}
+
+var ContentDispositionHeader = []byte("Content-Disposition")
+var nameParam = []byte("name")
+var filenameParam = []byte("filename")
+
+// FormName returns the name parameter of the part's Content-Disposition header.
+func (p *Part) FormName() []byte {
+	h, ok := p.GetHeader(ContentDispositionHeader)
+	if !ok {
+		return nil
+	}
+	v, _ := HeaderParam(h.RawValue, nameParam)
+	return v
+}
+
+// FileName returns the filename parameter of the part's Content-Disposition header.
+func (p *Part) FileName() []byte {
+	h, ok := p.GetHeader(ContentDispositionHeader)
+	if !ok {
+		return nil
+	}
+	v, _ := HeaderParam(h.RawValue, filenameParam)
+	return v
+}
+
+// HeaderParam returns the value of the parameter key in a header value such as
+// `form-data; name="file"; filename="a.txt"`. Quotes are removed but escapes are not decoded.
+func HeaderParam(value, key []byte) ([]byte, bool) {
+	// Skip the main value
+	semiIndex := indexByte(value, ';')
+	for semiIndex != -1 {
+		value = value[semiIndex+1:]
+
+		var param []byte
+		var quoted bool
+		param, value, quoted = nextHeaderParam(value)
+
+		name, v := param, param[len(param):]
+		if eqIndex := indexByte(param, '='); eqIndex != -1 {
+			name = param[:eqIndex]
+			v = param[eqIndex+1:]
+		}
+		if stricmp(trimLWS(name), key) {
+			v = trimLWS(v)
+			if quoted && len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+				v = v[1 : len(v)-1]
+			}
+			return v, true
+		}
+
+		semiIndex = indexByte(value, ';')
+	}
+	return nil, false
+}
+
+// nextHeaderParam splits value at the first ';' that is not inside a quoted string.
+func nextHeaderParam(value []byte) (param, rest []byte, quoted bool) {
+	inQuotes := false
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case ';':
+			if !inQuotes {
+				return value[:i], value[i:], quoted
+			}
+		}
+	}
+	return value, value[len(value):], quoted
+}
@@ -0,0 +1,218 @@
+package h1
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type testPart struct {
+	name     string
+	filename string
+	body     string
+}
+
+func buildMultipart(t testing.TB, parts []testPart) (body []byte, boundary string) {
+	var buffer bytes.Buffer
+	w := multipart.NewWriter(&buffer)
+	for _, p := range parts {
+		var pw io.Writer
+		var err error
+		if p.filename != "" {
+			pw, err = w.CreateFormFile(p.name, p.filename)
+		} else {
+			pw, err = w.CreateFormField(p.name)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pw.Write([]byte(p.body))
+	}
+	w.Close()
+	return buffer.Bytes(), w.Boundary()
+}
+
+func readParts(mr *MultipartReader) ([]testPart, error) {
+	var parts []testPart
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return parts, err
+		}
+		body, err := io.ReadAll(p)
+		if err != nil {
+			return parts, err
+		}
+		parts = append(parts, testPart{string(p.FormName()), string(p.FileName()), string(body)})
+	}
+}
+
+func Test_MultipartReader(t *testing.T) {
+	large := strings.Repeat("0123456789abcdef\r\n-", 2000)
+	tests := []struct {
+		name  string
+		parts []testPart
+	}{
+		{"no parts", nil},
+		{"single field", []testPart{{"a", "", "1"}}},
+		{"empty field", []testPart{{"a", "", ""}}},
+		{"fields and file", []testPart{{"a", "", "1"}, {"b", "", "two"}, {"file", "a.txt", "hello\r\nworld\r\n"}}},
+		{"large file", []testPart{{"file", "large.bin", large}, {"after", "", "x"}}},
+		{"boundary-like content", []testPart{{"a", "", "\r\n--notboundary\r\n-\r\n--"}}},
+	}
+
+	readers := map[string]func(io.Reader) io.Reader{
+		"full":     func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+	}
+
+	for _, tt := range tests {
+		for readerName, wrap := range readers {
+			t.Run(tt.name+"/"+readerName, func(t *testing.T) {
+				body, boundary := buildMultipart(t, tt.parts)
+				mr, err := NewMultipartReader(wrap(bytes.NewReader(body)), []byte(boundary))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer mr.Close()
+
+				got, err := readParts(mr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != len(tt.parts) {
+					t.Fatalf("got %d parts, want %d", len(got), len(tt.parts))
+				}
+				for i := range got {
+					if got[i] != tt.parts[i] {
+						t.Errorf("part %d = %+v, want %+v", i, got[i], tt.parts[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func Test_MultipartReader_Preamble(t *testing.T) {
+	body := "preamble\r\n--xyz  \r\nContent-Disposition: form-data; name=\"a;b\"\r\nX-Extra: 1\r\n\r\nvalue\r\n--xyz\r\nContent-Disposition: form-data; name=c\r\n\r\n\r\n--xyz--\r\nepilogue"
+	mr, err := NewMultipartReader(strings.NewReader(body), []byte("xyz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	p, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p.FormName()) != "a;b" {
+		t.Errorf("FormName() = %q, want %q", p.FormName(), "a;b")
+	}
+	if h, ok := p.GetHeader([]byte("x-extra")); !ok || string(h.RawValue) != "1" {
+		t.Errorf("GetHeader(x-extra) = %v, %v", h, ok)
+	}
+	// Skip the body of the first part
+	p, err = mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(p); string(p.FormName()) != "c" || len(b) != 0 {
+		t.Errorf("part = %q %q, want %q %q", p.FormName(), b, "c", "")
+	}
+	if _, err = mr.NextPart(); err != io.EOF {
+		t.Errorf("NextPart() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func Test_MultipartReader_Limits(t *testing.T) {
+	var parts []testPart
+	for i := 0; i < 5; i++ {
+		parts = append(parts, testPart{fmt.Sprintf("field%d", i), "", "value"})
+	}
+	body, boundary := buildMultipart(t, parts)
+
+	tests := []struct {
+		name  string
+		setup func(mr *MultipartReader)
+		body  []byte
+		want  error
+	}{
+		{"max parts", func(mr *MultipartReader) { mr.MaxParts = 3 }, body, ErrMultipartTooManyParts},
+		{"max size", func(mr *MultipartReader) { mr.MaxSize = 100 }, body, ErrMultipartTooLarge},
+		{"max header size", func(mr *MultipartReader) { mr.MaxHeaderSize = 32 }, body, ErrMultipartHeaderTooLarge},
+		{"truncated", func(mr *MultipartReader) {}, body[:len(body)-10], io.ErrUnexpectedEOF},
+		{"missing crlf", func(mr *MultipartReader) {}, []byte("--" + boundary + "garbage\r\n\r\n"), ErrMultipartMalformed},
+		{"invalid part header", func(mr *MultipartReader) {}, []byte("--" + boundary + "\r\nno colon\r\n\r\ndata\r\n--" + boundary + "--\r\n"), ErrMultipartMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, err := NewMultipartReader(bytes.NewReader(tt.body), []byte(boundary))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer mr.Close()
+			tt.setup(mr)
+
+			_, err = readParts(mr)
+			if err != tt.want {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_RequestReader_MultipartReader(t *testing.T) {
+	body, boundary := buildMultipart(t, []testPart{{"a", "", "1"}, {"file", "f.txt", "data"}})
+	r := newTestRequestReader(fmt.Sprintf("POST /upload HTTP/1.1\r\nContent-Type: multipart/form-data; boundary=%s\r\nContent-Length: %d\r\n\r\n%s", boundary, len(body), body))
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readParts(mr)
+	mr.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].body != "data" || got[1].filename != "f.txt" {
+		t.Errorf("parts = %+v", got)
+	}
+
+	r = newTestRequestReader("POST / HTTP/1.1\r\nContent-Type: application/json\r\nContent-Length: 0\r\n\r\n")
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.MultipartReader(); err != ErrNotMultipart {
+		t.Errorf("MultipartReader() error = %v, want %v", err, ErrNotMultipart)
+	}
+}
+
+func Benchmark_MultipartReader(b *testing.B) {
+	body, boundary := buildMultipart(b, []testPart{{"a", "", "1"}, {"file", "large.bin", strings.Repeat("x", 1<<20)}})
+	buffer := make([]byte, 32*1024)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mr, _ := NewMultipartReader(bytes.NewReader(body), []byte(boundary))
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			io.CopyBuffer(io.Discard, struct{ io.Reader }{p}, buffer)
+		}
+		mr.Close()
+	}
+}
@@ -0,0 +1,296 @@
+//go:build linux
+
+package h1
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Readiness is reported once per arming, so a connection is never handed to two goroutines.
+const pollEvents = syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT
+
+// pollWaitMillis bounds EpollWait so that idle timeouts are checked and the loop can exit.
+const pollWaitMillis = 1000
+
+// poller is the epoll event loop of a listener in Netpoll mode.
+type poller struct {
+	s     *Server
+	ln    net.Listener
+	pools *connPools
+
+	// wait is syscall.EpollWait, replaced in tests
+	wait func(epfd int, events []syscall.EpollEvent, msec int) (int, error)
+
+	mu     sync.Mutex
+	epfd   int // -1 once closed
+	conns  map[int]*pollConn
+	closed bool  // the listener is closed, exit once conns is empty
+	err    error // the error that stopped the event loop
+}
+
+// pollConn is a connection owned by a poller. It only holds buffers while it is served.
+type pollConn struct {
+	p  *poller
+	sc *serverConn
+	fd int
+
+	serving    uint32
+	closed     uint32
+	lastActive int64 // UnixNano
+}
+
+func (s *Server) serveNetpoll(ln net.Listener, pools *connPools) error {
+	p, err := newPoller(s, ln, pools)
+	if err != nil {
+		return err
+	}
+	return p.serve()
+}
+
+func newPoller(s *Server, ln net.Listener, pools *connPools) (*poller, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	return &poller{
+		s:     s,
+		ln:    ln,
+		pools: pools,
+		wait:  syscall.EpollWait,
+		epfd:  epfd,
+		conns: make(map[int]*pollConn),
+	}, nil
+}
+
+// serve accepts the connections of the listener until it is closed.
+// If the event loop fails first, its error is returned.
+func (p *poller) serve() error {
+	go p.run()
+
+	err := p.s.acceptLoop(p.ln, p.add)
+
+	p.mu.Lock()
+	p.closed = true
+	if p.err != nil {
+		err = p.err
+	}
+	p.mu.Unlock()
+	return err
+}
+
+// ctl is syscall.EpollCtl on the poller, which fails once the event loop has exited. p.mu must be held.
+func (p *poller) ctl(op, fd int) error {
+	if p.epfd < 0 {
+		return syscall.EBADF
+	}
+	return syscall.EpollCtl(p.epfd, op, fd, &syscall.EpollEvent{Events: pollEvents, Fd: int32(fd)})
+}
+
+// connFD returns the file descriptor of conn, or -1.
+func connFD(conn net.Conn) int {
+	sconn, ok := conn.(syscall.Conn)
+	if !ok {
+		return -1
+	}
+	raw, err := sconn.SyscallConn()
+	if err != nil {
+		return -1
+	}
+	fd := -1
+	raw.Control(func(f uintptr) {
+		fd = int(f)
+	})
+	return fd
+}
+
+// add registers a new connection. Connections without a file descriptor get their own goroutine.
+func (p *poller) add(conn net.Conn) {
+	s := p.s
+	fd := connFD(conn)
+	if fd < 0 {
+		go s.serveConn(conn, p.pools)
+		return
+	}
+
+	sc := newServerConn(conn)
+	pc := &pollConn{p: p, sc: sc, fd: fd}
+	sc.close = pc.close
+	err := s.trackConn(sc)
+	if err != nil {
+		if err == ErrTooManyConnections && !s.CloseOverLimit {
+			s.writeOverLimit(conn)
+		}
+		conn.Close()
+		return
+	}
+	pc.touch()
+
+	// Shutdown may have closed pc already, its descriptor may then belong to another connection
+	p.mu.Lock()
+	if atomic.LoadUint32(&pc.closed) == 0 {
+		p.conns[fd] = pc
+		err = p.ctl(syscall.EPOLL_CTL_ADD, fd)
+	}
+	p.mu.Unlock()
+	if err != nil {
+		pc.close()
+	}
+}
+
+func (p *poller) run() {
+	events := make([]syscall.EpollEvent, 256)
+	lastSweep := time.Now()
+
+	// Only run closes epfd
+	epfd := p.epfd
+	defer func() {
+		p.mu.Lock()
+		syscall.Close(epfd)
+		p.epfd = -1
+		p.mu.Unlock()
+	}()
+
+	for {
+		n, err := p.wait(epfd, events, pollWaitMillis)
+		if err != nil && err != syscall.EINTR {
+			p.fail(err)
+			return
+		}
+
+		for i := 0; i < n; i++ {
+			p.mu.Lock()
+			pc := p.conns[int(events[i].Fd)]
+			p.mu.Unlock()
+
+			// A stale event of a closed descriptor may name a connection that is already served
+			if pc != nil && atomic.CompareAndSwapUint32(&pc.serving, 0, 1) {
+				go pc.serve()
+			}
+		}
+
+		if now := time.Now(); now.Sub(lastSweep) >= time.Second {
+			lastSweep = now
+			p.closeIdle(now)
+		}
+
+		p.mu.Lock()
+		done := p.closed && len(p.conns) == 0
+		p.mu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// fail stops serving after an error of the event loop: the listener and every connection are closed.
+func (p *poller) fail(err error) {
+	p.mu.Lock()
+	p.err = err
+	conns := make([]*pollConn, 0, len(p.conns))
+	for _, pc := range p.conns {
+		conns = append(conns, pc)
+	}
+	p.mu.Unlock()
+
+	p.ln.Close()
+	for _, pc := range conns {
+		pc.close()
+	}
+}
+
+// closeIdle closes the connections waiting for a request for longer than the idle timeout.
+func (p *poller) closeIdle(now time.Time) {
+	timeout := p.s.idleTimeout()
+	if timeout <= 0 {
+		return
+	}
+	limit := now.Add(-timeout).UnixNano()
+
+	var idle []*pollConn
+	p.mu.Lock()
+	for _, pc := range p.conns {
+		if atomic.LoadUint32(&pc.serving) == 0 && atomic.LoadInt64(&pc.lastActive) < limit {
+			idle = append(idle, pc)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, pc := range idle {
+		pc.close()
+	}
+}
+
+func (pc *pollConn) touch() {
+	atomic.StoreInt64(&pc.lastActive, time.Now().UnixNano())
+}
+
+// serve serves the readable connection until it goes idle, then re-arms it.
+func (pc *pollConn) serve() {
+	s := pc.p.s
+	pools := pc.p.pools
+	conn := pc.sc.conn
+
+	buffer := pools.getBuffer()
+	reader := pools.getRequestReader(conn, 0)
+	reader.ReadBuffer = (*buffer)[:cap(*buffer)]
+	reader.Reset()
+
+	resp := pools.getResponse(conn)
+	resp.DateServerHeaderFunc = s.dateServerHeaderFunc()
+
+	idle, err := s.serveRequests(pc.sc, reader, resp, true)
+
+	PutResponse(resp)
+	reader.ReadBuffer = nil
+	putRequestReader(reader)
+	pools.putBuffer(buffer)
+
+	if !idle || err != nil {
+		pc.close()
+		return
+	}
+
+	pc.touch()
+
+	// Re-arm under the lock: once pc is closed, its descriptor may belong to a new connection.
+	// serving is cleared first, the event of the re-armed descriptor could be missed otherwise.
+	p := pc.p
+	p.mu.Lock()
+	if atomic.LoadUint32(&pc.closed) != 0 {
+		p.mu.Unlock()
+		return
+	}
+	atomic.StoreUint32(&pc.serving, 0)
+	err = p.ctl(syscall.EPOLL_CTL_MOD, pc.fd)
+	p.mu.Unlock()
+	if err != nil {
+		pc.close()
+	}
+}
+
+// close unregisters and closes the connection. It may be called any number of times.
+func (pc *pollConn) close() {
+	if !atomic.CompareAndSwapUint32(&pc.closed, 0, 1) {
+		return
+	}
+
+	// Remove the descriptor before it can be reused by a new connection.
+	// pc is no longer registered if its descriptor was closed elsewhere (e.g. by a hijacking handler)
+	// and reused by a new connection, which must stay registered.
+	p := pc.p
+	p.mu.Lock()
+	if p.conns[pc.fd] == pc {
+		delete(p.conns, pc.fd)
+		if p.epfd >= 0 {
+			syscall.EpollCtl(p.epfd, syscall.EPOLL_CTL_DEL, pc.fd, nil)
+		}
+	}
+	p.mu.Unlock()
+
+	pc.sc.conn.Close()
+	p.s.untrackConn(pc.sc)
+}
@@ -0,0 +1,152 @@
+package h1
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_Poller_WaitError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := &Server{Handler: helloHandler}
+	p, err := newPoller(s, ln, defaultConnPools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail := make(chan struct{})
+	p.wait = func(epfd int, events []syscall.EpollEvent, msec int) (int, error) {
+		select {
+		case <-fail:
+			return 0, syscall.EIO
+		default:
+			return syscall.EpollWait(epfd, events, 10)
+		}
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- p.serve()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if body, err := netpollGet(conn, bufio.NewReader(conn), "/a"); err != nil || body != "/a" {
+		t.Fatalf("GET /a = %q, %v", body, err)
+	}
+	waitFor(t, "idle connection", func() bool {
+		stats := s.Stats()
+		return stats.Open == 1 && stats.Idle == 1
+	})
+
+	// The connections of a failed event loop are closed, the error is returned by Serve
+	close(fail)
+	if err := <-served; err != syscall.EIO {
+		t.Errorf("serve() error = %v, want %v", err, syscall.EIO)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, 1)); n != 0 || err == nil {
+		t.Errorf("connection read %d bytes, err = %v", n, err)
+	}
+	if stats := s.Stats(); stats.Open != 0 {
+		t.Errorf("Stats() = %+v after the event loop failed", stats)
+	}
+}
+
+func Test_Poller_CloseReusedFD(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	s := &Server{Handler: helloHandler}
+	p, err := newPoller(s, ln, defaultConnPools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(p.epfd)
+
+	dial := func() {
+		client, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+	}
+	accept := func() (net.Conn, int) {
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.add(conn)
+		return conn, connFD(conn)
+	}
+
+	// A handler hijacks the first connection and closes it, the next accepted connection gets its descriptor
+	dial()
+	first, fd := accept()
+	old := p.conns[fd]
+	dial()
+	first.Close()
+	second, fd2 := accept()
+	defer second.Close()
+	if fd2 != fd {
+		t.Skipf("descriptor %d not reused, got %d", fd, fd2)
+	}
+
+	old.close()
+	p.mu.Lock()
+	registered := p.conns[fd] != nil && p.conns[fd] != old
+	err = p.ctl(syscall.EPOLL_CTL_MOD, fd)
+	p.mu.Unlock()
+	if !registered || err != nil {
+		t.Errorf("closing the old connection unregistered the new one: registered = %v, EPOLL_CTL_MOD error = %v", registered, err)
+	}
+}
+
+func Test_Poller_Timeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	release := make(chan struct{})
+	h := Timeout(10 * time.Millisecond)(func(resp *Response, req *RequestReader) error {
+		<-release
+		return nil
+	})
+	s := &Server{Handler: h, Netpoll: true}
+	go s.Serve(ln)
+	defer s.Shutdown(context.Background())
+	defer close(release)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "GET /a HTTP/1.1\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 503 {
+		t.Errorf("status = %d, want 503", res.StatusCode)
+	}
+
+	// The event loop closed the connection while the handler is still blocked
+	waitFor(t, "connection closed by the event loop", func() bool {
+		return s.Stats().Open == 0
+	})
+}
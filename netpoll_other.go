@@ -0,0 +1,14 @@
+//go:build !linux
+
+package h1
+
+import (
+	"net"
+)
+
+// serveNetpoll falls back to a goroutine per connection where epoll is not available.
+func (s *Server) serveNetpoll(ln net.Listener, pools *connPools) error {
+	return s.acceptLoop(ln, func(conn net.Conn) {
+		go s.serveConn(conn, pools)
+	})
+}
@@ -0,0 +1,123 @@
+package h1
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startNetpollServer(t *testing.T, s *Server) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	s.Netpoll = true
+	if s.Handler == nil {
+		s.Handler = helloHandler
+	}
+	go s.Serve(ln)
+	return ln
+}
+
+// netpollGet sends a GET request for path on conn and returns the body of the response.
+func netpollGet(conn net.Conn, br *bufio.Reader, path string) (string, error) {
+	_, err := fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: test\r\n\r\n", path)
+	if err != nil {
+		return "", err
+	}
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func Test_Server_Netpoll(t *testing.T) {
+	s := &Server{}
+	ln := startNetpollServer(t, s)
+	defer ln.Close()
+
+	var wg sync.WaitGroup
+	for c := 0; c < 20; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+
+			// The connection goes idle between the requests
+			for i := 0; i < 3; i++ {
+				path := fmt.Sprintf("/%d/%d", c, i)
+				body, err := netpollGet(conn, br, path)
+				if err != nil || body != path {
+					t.Errorf("GET %s = %q, %v", path, body, err)
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}(c)
+	}
+	wg.Wait()
+}
+
+func Test_Server_Netpoll_Idle(t *testing.T) {
+	s := &Server{}
+	ln := startNetpollServer(t, s)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if body, err := netpollGet(conn, bufio.NewReader(conn), "/a"); err != nil || body != "/a" {
+		t.Fatalf("GET /a = %q, %v", body, err)
+	}
+	waitFor(t, "idle connection", func() bool {
+		stats := s.Stats()
+		return stats.Open == 1 && stats.Idle == 1
+	})
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if n, err := conn.Read(make([]byte, 1)); n != 0 || err == nil {
+		t.Errorf("idle connection read %d bytes, err = %v", n, err)
+	}
+	if stats := s.Stats(); stats.Open != 0 {
+		t.Errorf("Stats() = %+v after Shutdown", stats)
+	}
+}
+
+func Test_Server_Netpoll_IdleTimeout(t *testing.T) {
+	s := &Server{IdleTimeout: 10 * time.Millisecond}
+	ln := startNetpollServer(t, s)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if body, err := netpollGet(conn, bufio.NewReader(conn), "/a"); err != nil || body != "/a" {
+		t.Fatalf("GET /a = %q, %v", body, err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("idle connection read %d bytes, err = %v", n, err)
+	}
+}
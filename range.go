@@ -0,0 +1,357 @@
+package h1
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ByteRange is a satisfiable range of a representation.
+type ByteRange struct {
+	Start, Length int64
+}
+
+// End returns the offset of the last byte in the range.
+func (br ByteRange) End() int64 {
+	return br.Start + br.Length - 1
+}
+
+var ErrInvalidRange = errors.New("invalid range")
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+var ErrTooManyRanges = errors.New("too many ranges")
+
+// DefaultMaxRanges is the maximum number of ranges in a Range header when RangeOptions.MaxRanges is zero.
+const DefaultMaxRanges = 16
+
+type RangeOptions struct {
+	// MaxRanges limits the number of ranges in a Range header (DefaultMaxRanges if zero, unlimited if negative).
+	MaxRanges int
+}
+
+var IfRangeHeader = []byte("If-Range")
+var RangeHeader = []byte("Range")
+
+var bytesUnit = []byte("bytes=")
+
+// ParseRange parses a Range header value for a representation of size bytes and appends the satisfiable
+// ranges to dst (RFC 9110 Section 14.1.2) with the default options.
+func ParseRange(value []byte, size int64, dst []ByteRange) ([]ByteRange, error) {
+	return ParseRangeOptions(value, size, dst, RangeOptions{})
+}
+
+// ParseRangeOptions parses a Range header value for a representation of size bytes and appends the
+// satisfiable ranges to dst, sorted and with overlapping or adjacent ranges coalesced.
+// ErrRangeNotSatisfiable is returned if none of the ranges is satisfiable.
+// Any other error means the Range header should be ignored.
+func ParseRangeOptions(value []byte, size int64, dst []ByteRange, opts RangeOptions) ([]ByteRange, error) {
+	if !hasPrefixFold(value, bytesUnit) {
+		return dst, ErrInvalidRange
+	}
+
+	maxRanges := opts.MaxRanges
+	if maxRanges == 0 {
+		maxRanges = DefaultMaxRanges
+	}
+
+	n := len(dst)
+	count := 0
+	next := value[len(bytesUnit):]
+	for len(next) > 0 {
+		var element []byte
+		element, next = next, nil
+		if commaIndex := indexByte(element, ','); commaIndex != -1 {
+			element, next = element[:commaIndex], element[commaIndex+1:]
+		}
+		element = trimLWS(element)
+		if len(element) == 0 {
+			continue
+		}
+
+		count++
+		if maxRanges > 0 && count > maxRanges {
+			return dst[:n], ErrTooManyRanges
+		}
+
+		dashIndex := indexByte(element, '-')
+		if dashIndex == -1 {
+			return dst[:n], ErrInvalidRange
+		}
+		first, last := element[:dashIndex], element[dashIndex+1:]
+
+		if len(first) == 0 {
+			// suffix-range = "-" suffix-length
+			suffix, err := parseRangeInt(last)
+			if err != nil {
+				return dst[:n], err
+			}
+			if suffix == 0 || size == 0 {
+				continue
+			}
+			if suffix > size {
+				suffix = size
+			}
+			dst = append(dst, ByteRange{Start: size - suffix, Length: suffix})
+			continue
+		}
+
+		start, err := parseRangeInt(first)
+		if err != nil {
+			return dst[:n], err
+		}
+		end := size - 1
+		if len(last) > 0 {
+			end, err = parseRangeInt(last)
+			if err != nil {
+				return dst[:n], err
+			}
+			if end < start {
+				return dst[:n], ErrInvalidRange
+			}
+			if end >= size {
+				end = size - 1
+			}
+		}
+		if start >= size {
+			continue
+		}
+		dst = append(dst, ByteRange{Start: start, Length: end - start + 1})
+	}
+
+	if count == 0 {
+		return dst, ErrInvalidRange
+	}
+	if len(dst) == n {
+		return dst, ErrRangeNotSatisfiable
+	}
+	return append(dst[:n], coalesceRanges(dst[n:])...), nil
+}
+
+func parseRangeInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 18 {
+		return 0, ErrInvalidRange
+	}
+	var v int64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, ErrInvalidRange
+		}
+		v = v*10 + int64(c-'0')
+	}
+	return v, nil
+}
+
+// coalesceRanges sorts ranges by offset and merges the ones that overlap or touch, in place.
+// Clients asking for many small overlapping ranges would otherwise get the same bytes repeatedly.
+func coalesceRanges(ranges []ByteRange) []ByteRange {
+	// Insertion sort, the number of ranges is limited
+	for i := 1; i < len(ranges); i++ {
+		for j := i; j > 0 && ranges[j].Start < ranges[j-1].Start; j-- {
+			ranges[j], ranges[j-1] = ranges[j-1], ranges[j]
+		}
+	}
+
+	merged := ranges[:0]
+	for _, br := range ranges {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			if br.Start <= last.End()+1 {
+				if br.End() > last.End() {
+					last.Length = br.End() - last.Start + 1
+				}
+				continue
+			}
+		}
+		merged = append(merged, br)
+	}
+	return merged
+}
+
+// IfRangeMatch reports whether the If-Range precondition of the request holds for a representation with
+// the given validators (RFC 9110 Section 13.1.5). It holds if the request has no If-Range header.
+// An entity-tag must match strongly, a date must equal modtime exactly.
+func (r *Request) IfRangeMatch(etag []byte, modtime time.Time) bool {
+	h, ok := r.GetHeader(IfRangeHeader)
+	if !ok {
+		return true
+	}
+	value := trimLWS(h.RawValue)
+	if len(value) > 0 && (value[0] == '"' || value[0] == 'W') {
+		// Weak entity-tags never match
+		return len(etag) > 0 && !isWeakETag(etag) && string(value) == string(etag)
+	}
+	t, err := ParseHTTPDate(value)
+	return err == nil && modtime.Unix() == t.Unix()
+}
+
+// Ranges parses the Range header of a GET request for a representation of size bytes and appends the
+// satisfiable ranges to dst. No ranges are returned if the request has no Range header or its If-Range
+// precondition does not hold for etag and modtime.
+// The errors are those of ParseRangeOptions.
+func (r *Request) Ranges(size int64, etag []byte, modtime time.Time, dst []ByteRange) ([]ByteRange, error) {
+	if r.Method != MethodGET {
+		return dst, nil
+	}
+	h, ok := r.GetHeader(RangeHeader)
+	if !ok || !r.IfRangeMatch(etag, modtime) {
+		return dst, nil
+	}
+	return ParseRange(h.RawValue, size, dst)
+}
+
+var byteRangePool = sync.Pool{
+	New: func() any {
+		v := make([]ByteRange, 0, 8)
+		return &v
+	},
+}
+
+// GetByteRanges returns an empty pooled slice for Ranges.
+func GetByteRanges() *[]ByteRange {
+	v := byteRangePool.Get().(*[]ByteRange)
+	*v = (*v)[:0]
+	return v
+}
+
+func PutByteRanges(v *[]ByteRange) {
+	byteRangePool.Put(v)
+}
+
+var contentRangeHeader = []byte("Content-Range: bytes ")
+var acceptRangesBytesHeader = []byte("Accept-Ranges: bytes\r\n")
+var multipartByterangesHeader = []byte("Content-Type: multipart/byteranges; boundary=")
+
+// WriteContentRange writes the Content-Range header line of br.
+func (r *Response) WriteContentRange(br ByteRange, size int64) error {
+	r.Write(contentRangeHeader)
+	r.WriteInt64(br.Start)
+	r.WriteString("-")
+	r.WriteInt64(br.End())
+	r.WriteString("/")
+	r.WriteInt64(size)
+	_, err := r.Write(crlf)
+	return err
+}
+
+// WriteRangeNotSatisfiable writes a complete 416 response for a representation of size bytes.
+func (r *Response) WriteRangeNotSatisfiable(size int64) error {
+	r.ContentLength = 0
+	err := r.WriteHeader(416)
+	if err != nil {
+		return err
+	}
+	r.Write(contentRangeHeader)
+	r.WriteString("*/")
+	r.WriteInt64(size)
+	r.Write(crlf)
+	return r.EndHeader()
+}
+
+// WritePartialHeader writes the 206 status line, the standard headers and the headers describing ranges
+// of a representation of size bytes. A single range gets contentType and Content-Range, multiple ranges
+// get a multipart/byteranges Content-Type. More headers may follow before EndHeader.
+// The body is written with WriteRanges.
+func (r *Response) WritePartialHeader(ranges []ByteRange, size int64, contentType []byte) error {
+	if len(ranges) == 1 {
+		r.ContentLength = ranges[0].Length
+		err := r.WriteHeader(206)
+		if err != nil {
+			return err
+		}
+		if len(contentType) > 0 {
+			r.Write(contentTypeHeader)
+			r.Write(contentType)
+			r.Write(crlf)
+		}
+		return r.WriteContentRange(ranges[0], size)
+	}
+
+	rand.Read(r.boundaryRaw[:])
+	hex.Encode(r.boundary[:], r.boundaryRaw[:])
+
+	partHeader := GetBuffer()
+	defer PutBuffer(partHeader)
+
+	// The Content-Length is the sum of the part headers, the parts and the final delimiter
+	var total int64
+	for i, br := range ranges {
+		*partHeader = r.appendRangePartHeader((*partHeader)[:0], contentType, br, size, i == 0)
+		total += int64(len(*partHeader)) + br.Length
+	}
+	total += int64(len("\r\n--") + len(r.boundary) + len("--\r\n"))
+
+	r.ContentLength = total
+	err := r.WriteHeader(206)
+	if err != nil {
+		return err
+	}
+	r.Write(multipartByterangesHeader)
+	r.Write(r.boundary[:])
+	_, err = r.Write(crlf)
+	return err
+}
+
+// appendRangePartHeader appends the delimiter and headers preceding a part of a multipart/byteranges body.
+func (r *Response) appendRangePartHeader(dst, contentType []byte, br ByteRange, size int64, first bool) []byte {
+	if !first {
+		dst = append(dst, crlf...)
+	}
+	dst = append(dst, "--"...)
+	dst = append(dst, r.boundary[:]...)
+	dst = append(dst, crlf...)
+	if len(contentType) > 0 {
+		dst = append(dst, contentTypeHeader...)
+		dst = append(dst, contentType...)
+		dst = append(dst, crlf...)
+	}
+	dst = append(dst, contentRangeHeader...)
+	dst = strconv.AppendInt(dst, br.Start, 10)
+	dst = append(dst, '-')
+	dst = strconv.AppendInt(dst, br.End(), 10)
+	dst = append(dst, '/')
+	dst = strconv.AppendInt(dst, size, 10)
+	dst = append(dst, crlf...)
+	return append(dst, crlf...)
+}
+
+// WriteRanges writes the body of a 206 response started with WritePartialHeader, reading the ranges from src.
+// The arguments must be the same as for WritePartialHeader. An *os.File is sent with SendFile.
+func (r *Response) WriteRanges(src io.ReaderAt, ranges []ByteRange, size int64, contentType []byte) error {
+	if len(ranges) == 1 {
+		return r.writeRange(src, ranges[0])
+	}
+
+	partHeader := GetBuffer()
+	defer PutBuffer(partHeader)
+
+	for i, br := range ranges {
+		*partHeader = r.appendRangePartHeader((*partHeader)[:0], contentType, br, size, i == 0)
+		_, err := r.Write(*partHeader)
+		if err != nil {
+			return err
+		}
+		err = r.writeRange(src, br)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.WriteString("\r\n--")
+	r.Write(r.boundary[:])
+	_, err := r.WriteString("--\r\n")
+	return err
+}
+
+func (r *Response) writeRange(src io.ReaderAt, br ByteRange) error {
+	var err error
+	if f, ok := src.(*os.File); ok {
+		_, err = r.SendFile(f, br.Start, br.Length)
+	} else {
+		_, err = r.copyReaderAt(src, br.Start, br.Length)
+	}
+	return err
+}
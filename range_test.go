@@ -0,0 +1,185 @@
+package h1
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ParseRange(t *testing.T) {
+	tests := []struct {
+		value     string
+		size      int64
+		maxRanges int
+		want      []ByteRange
+		wantErr   error
+	}{
+		{"bytes=0-499", 10000, 0, []ByteRange{{0, 500}}, nil},
+		{"bytes=500-999", 10000, 0, []ByteRange{{500, 500}}, nil},
+		{"bytes=-500", 10000, 0, []ByteRange{{9500, 500}}, nil},
+		{"bytes=9500-", 10000, 0, []ByteRange{{9500, 500}}, nil},
+		{"BYTES=0-0", 10000, 0, []ByteRange{{0, 1}}, nil},
+		{"bytes=0-0,-1", 10000, 0, []ByteRange{{0, 1}, {9999, 1}}, nil},
+		{"bytes= 500-600 , 601-999 ,", 10000, 0, []ByteRange{{500, 500}}, nil},
+		{"bytes=500-700,601-999", 10000, 0, []ByteRange{{500, 500}}, nil},
+		{"bytes=900-999,0-99,50-149", 10000, 0, []ByteRange{{0, 150}, {900, 100}}, nil},
+		{"bytes=0-,100-200", 10000, 0, []ByteRange{{0, 10000}}, nil},
+		{"bytes=0-99999", 10000, 0, []ByteRange{{0, 10000}}, nil},
+		{"bytes=-99999", 10000, 0, []ByteRange{{0, 10000}}, nil},
+		{"bytes=10000-,0-1", 10000, 0, []ByteRange{{0, 2}}, nil},
+		{"bytes=10000-", 10000, 0, nil, ErrRangeNotSatisfiable},
+		{"bytes=-0", 10000, 0, nil, ErrRangeNotSatisfiable},
+		{"bytes=0-", 0, 0, nil, ErrRangeNotSatisfiable},
+		{"bytes=", 10000, 0, nil, ErrInvalidRange},
+		{"bytes= , ", 10000, 0, nil, ErrInvalidRange},
+		{"bytes=1-0", 10000, 0, nil, ErrInvalidRange},
+		{"bytes=a-b", 10000, 0, nil, ErrInvalidRange},
+		{"bytes=1", 10000, 0, nil, ErrInvalidRange},
+		{"bytes=--1", 10000, 0, nil, ErrInvalidRange},
+		{"bytes=1-2-3", 10000, 0, nil, ErrInvalidRange},
+		{"bytes=0-99999999999999999999", 10000, 0, nil, ErrInvalidRange},
+		{"items=0-1", 10000, 0, nil, ErrInvalidRange},
+		{"0-1", 10000, 0, nil, ErrInvalidRange},
+		{"bytes=0-1,2-3,4-5", 10000, 2, nil, ErrTooManyRanges},
+		{"bytes=0-1,2-3,4-5", 10000, -1, []ByteRange{{0, 6}}, nil},
+		{"bytes=0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0,0-0", 10000, 0, nil, ErrTooManyRanges},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRangeOptions([]byte(tt.value), tt.size, nil, RangeOptions{MaxRanges: tt.maxRanges})
+			if err != tt.wantErr {
+				t.Fatalf("ParseRangeOptions() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRangeOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Request_Ranges(t *testing.T) {
+	etag := []byte(`"v1"`)
+	modtime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	date := modtime.Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		request string
+		want    []ByteRange
+	}{
+		{"No Range", "GET / HTTP/1.1\r\n\r\n", nil},
+		{"Range", "GET / HTTP/1.1\r\nRange: bytes=0-9\r\n\r\n", []ByteRange{{0, 10}}},
+		{"HEAD", "HEAD / HTTP/1.1\r\nRange: bytes=0-9\r\n\r\n", nil},
+		{"If-Range ETag", "GET / HTTP/1.1\r\nRange: bytes=0-9\r\nIf-Range: \"v1\"\r\n\r\n", []ByteRange{{0, 10}}},
+		{"If-Range Other ETag", "GET / HTTP/1.1\r\nRange: bytes=0-9\r\nIf-Range: \"v2\"\r\n\r\n", nil},
+		{"If-Range Weak ETag", "GET / HTTP/1.1\r\nRange: bytes=0-9\r\nIf-Range: W/\"v1\"\r\n\r\n", nil},
+		{"If-Range Date", "GET / HTTP/1.1\r\nRange: bytes=0-9\r\nIf-Range: " + date + "\r\n\r\n", []ByteRange{{0, 10}}},
+		{"If-Range Old Date", "GET / HTTP/1.1\r\nRange: bytes=0-9\r\nIf-Range: " + modtime.Add(-time.Second).Format(http.TimeFormat) + "\r\n\r\n", nil},
+		{"If-Range Invalid", "GET / HTTP/1.1\r\nRange: bytes=0-9\r\nIf-Range: soon\r\n\r\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newTestRequestReader(tt.request)
+			if _, err := reader.Next(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := reader.Request.Ranges(100, etag, modtime, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("Ranges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Response_WriteRanges(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	src := bytes.NewReader(data)
+	size := int64(len(data))
+
+	tests := []struct {
+		name   string
+		ranges []ByteRange
+	}{
+		{"Single", []ByteRange{{100, 200}}},
+		{"Large", []ByteRange{{1, size - 1}}},
+		{"Multiple", []ByteRange{{0, 1}, {10, 5000}, {size - 100, 100}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			resp := GetResponse(&out)
+			defer PutResponse(resp)
+
+			contentType := []byte("text/plain")
+			if err := resp.WritePartialHeader(tt.ranges, size, contentType); err != nil {
+				t.Fatal(err)
+			}
+			if err := resp.EndHeader(); err != nil {
+				t.Fatal(err)
+			}
+			if err := resp.WriteRanges(src, tt.ranges, size, contentType); err != nil {
+				t.Fatal(err)
+			}
+			if err := resp.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := http.ReadResponse(bufio.NewReader(&out), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != 206 {
+				t.Fatalf("status = %d, want 206", res.StatusCode)
+			}
+			body, _ := io.ReadAll(res.Body)
+			if int64(len(body)) != res.ContentLength {
+				t.Fatalf("Content-Length = %d, body is %d bytes", res.ContentLength, len(body))
+			}
+
+			if len(tt.ranges) == 1 {
+				br := tt.ranges[0]
+				if !bytes.Equal(body, data[br.Start:br.Start+br.Length]) {
+					t.Errorf("body mismatch")
+				}
+				return
+			}
+
+			mediaType, params, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+			if mediaType != "multipart/byteranges" {
+				t.Fatalf("Content-Type = %q", res.Header.Get("Content-Type"))
+			}
+			mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+			for i, br := range tt.ranges {
+				part, err := mr.NextPart()
+				if err != nil {
+					t.Fatalf("part %d: %v", i, err)
+				}
+				got, _ := io.ReadAll(part)
+				if !bytes.Equal(got, data[br.Start:br.Start+br.Length]) {
+					t.Errorf("part %d mismatch", i)
+				}
+				if part.Header.Get("Content-Type") != "text/plain" {
+					t.Errorf("part %d Content-Type = %q", i, part.Header.Get("Content-Type"))
+				}
+			}
+			if _, err := mr.NextPart(); err != io.EOF {
+				t.Errorf("NextPart() error = %v, want EOF", err)
+			}
+		})
+	}
+}
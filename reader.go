@@ -12,12 +12,31 @@ type RequestReader struct {
 	NextBuffer []byte
 
 	Request Request
+
+	// MaxFormSize limits the body size read by ParseForm (DefaultMaxFormSize if zero)
+	MaxFormSize int64
+
+	// MaxDecompressedSize limits the body size returned by DecodedBody (DefaultMaxDecompressedSize if zero or negative)
+	MaxDecompressedSize int64
+
+	// Unread bytes of the current request body
+	bodyRemaining int64
+
+	// Pool of the BodyReaders returned by Body (BodyReaderPool if nil)
+	bodyReaders *sync.Pool
+
+	// Pool of a Server accept loop (requestReaderPool if nil)
+	pool *sync.Pool
+
+	// Connection of the Server reading the requests (nil outside of a Server)
+	conn *serverConn
 }
 
 func (r *RequestReader) Reset() {
 	r.ReadBuffer = r.ReadBuffer[:cap(r.ReadBuffer)]
 	r.NextBuffer = r.ReadBuffer[:0]
 	r.Request.Reset()
+	r.bodyRemaining = 0
 }
 
 func (r *RequestReader) Fill() (n int, err error) {
@@ -37,7 +56,11 @@ func (r *RequestReader) Fill() (n int, err error) {
 }
 
 func (r *RequestReader) Next() (remaining int, err error) {
-	var retryCount int = 0
+	// Skip the unread body of the previous request
+	err = r.DiscardBody()
+	if err != nil {
+		return 0, err
+	}
 
 	if r.Remaining() == 0 {
 		n, err := r.R.Read(r.ReadBuffer[:cap(r.ReadBuffer)])
@@ -47,62 +70,72 @@ func (r *RequestReader) Next() (remaining int, err error) {
 		r.NextBuffer = r.ReadBuffer[:n]
 	}
 
-parse:
-	// Reset the request
-	r.Request.Reset()
-
-	// Read request line
-	r.NextBuffer, err = ParseRequestLine(&r.Request, r.NextBuffer)
-	if err != nil {
-		if err == ErrBufferTooSmall {
-			// Buffer is too small, read more bytes
+	for {
+		// Reset the request
+		r.Request.Reset()
 
-			_, err = r.Fill()
-			if err != nil {
-				return 0, err
-			}
+		// Read request line and headers
+		next, err := ParseRequestLine(&r.Request, r.NextBuffer)
+		if err == nil {
+			next, err = ParseHeaders(&r.Request, next)
+		}
+		if err == nil {
+			r.NextBuffer = next
+			break
+		}
+		if err != ErrBufferTooSmall {
+			return 0, err
+		}
 
-			if len(r.NextBuffer) == cap(r.ReadBuffer) {
-				return 0, ErrRequestHeaderTooLarge
-			}
+		if len(r.NextBuffer) == cap(r.ReadBuffer) {
+			return 0, ErrRequestHeaderTooLarge
+		}
 
-			// Retry parsing
-			retryCount++
-			if retryCount > 1 {
-				return 0, ErrBufferTooSmall
+		// The head may arrive in any number of segments, read until it is complete.
+		// The read deadline of the connection bounds the wait.
+		_, err = r.Fill()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
 			}
-			goto parse
+			return 0, err
 		}
 	}
 
-	// Read headers
-	r.NextBuffer, err = ParseHeaders(&r.Request, r.NextBuffer)
+	// Parse URI
+	err = r.Request.URI.Parse(r.Request.RawURI)
 	if err != nil {
-		if err == ErrBufferTooSmall {
-			// Buffer is too small, read more bytes
+		return 0, err
+	}
 
-			_, err = r.Fill()
-			if err != nil {
-				return 0, err
-			}
+	if r.Request.ContentLength > 0 {
+		r.bodyRemaining = r.Request.ContentLength
+	}
 
-			if len(r.NextBuffer) == cap(r.ReadBuffer) {
-				return 0, ErrRequestHeaderTooLarge
-			}
+	return len(r.NextBuffer), nil
+}
 
-			// Retry parsing
-			retryCount++
-			if retryCount > 1 {
-				return 0, ErrBufferTooSmall
+// DiscardBody skips the unread part of the current request body. Next calls it before reading a request.
+func (r *RequestReader) DiscardBody() error {
+	for r.bodyRemaining > 0 {
+		if len(r.NextBuffer) == 0 {
+			_, err := r.Fill()
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return err
 			}
-			goto parse
 		}
-	}
-
-	// Parse URI
-	r.Request.URI.Parse(r.Request.RawURI)
 
-	return len(r.NextBuffer), nil
+		n := len(r.NextBuffer)
+		if int64(n) > r.bodyRemaining {
+			n = int(r.bodyRemaining)
+		}
+		r.NextBuffer = r.NextBuffer[n:]
+		r.bodyRemaining -= int64(n)
+	}
+	return nil
 }
 
 func (r *RequestReader) Remaining() int {
@@ -110,8 +143,13 @@ func (r *RequestReader) Remaining() int {
 }
 
 func (r *RequestReader) Body() *BodyReader {
-	br := GetBodyReader()
-	br.Limit = int(r.Request.ContentLength)
+	var br *BodyReader
+	if r.bodyReaders != nil {
+		br = r.bodyReaders.Get().(*BodyReader)
+	} else {
+		br = GetBodyReader()
+	}
+	br.Limit = int(r.bodyRemaining)
 	br.Upstream = r
 	return br
 }
@@ -121,6 +159,9 @@ type BodyReader struct {
 
 	Limit int
 	Index int
+
+	// Pool of a Server accept loop (BodyReaderPool if nil)
+	pool *sync.Pool
 }
 
 func (r *BodyReader) reset() {
@@ -140,8 +181,13 @@ func GetBodyReader() *BodyReader {
 	return BodyReaderPool.Get().(*BodyReader)
 }
 
+// PutBodyReader returns r to the pool it was taken from.
 func PutBodyReader(r *BodyReader) {
 	r.reset()
+	if r.pool != nil {
+		r.pool.Put(r)
+		return
+	}
 	BodyReaderPool.Put(r)
 }
 
@@ -155,38 +201,31 @@ func (r *BodyReader) Read(p []byte) (n int, err error) {
 		p = p[:r.Limit-r.Index]
 	}
 
-	// Copy the remaining bytes to the read buffer
-	n0 := copy(p, r.Upstream.NextBuffer)
-	r.Upstream.NextBuffer = r.Upstream.NextBuffer[n0:]
-	r.Index += n0
-
-	if len(p) == n0 {
-		// No more bytes to read
-		return n0, nil
-	}
+	n, err = r.Upstream.read(p)
+	r.Index += n
+	r.Upstream.bodyRemaining -= int64(n)
+	return n, err
+}
 
-	// Fill The buffer
-	_, err = r.Upstream.Fill()
-	if err != nil {
-		return n0, err
-	}
+// read copies buffered bytes to p, reading from R if there are none.
+func (r *RequestReader) read(p []byte) (n int, err error) {
+	if len(r.NextBuffer) == 0 {
+		// If p is bigger than ReadBuffer then read directly from the upstream
+		if len(p) >= cap(r.ReadBuffer) {
+			return r.R.Read(p)
+		}
 
-	// If p is bigger than Uptream.ReadBuffer then read directly from the upstream
-	if len(p)-n0 > len(r.Upstream.ReadBuffer) {
-		n1, err := r.Upstream.R.Read(p[n0:])
+		// Fill The buffer
+		_, err = r.Fill()
 		if err != nil {
-			return n0 + n1, err
+			return 0, err
 		}
-		return n0 + n1, nil
 	}
 
-	// Read more bytes
-	n1, err := r.Read(p[n0:])
-	if err != nil {
-		return n0 + n1, err
-	}
-
-	return n0 + n1, nil
+	// Copy the buffered bytes
+	n = copy(p, r.NextBuffer)
+	r.NextBuffer = r.NextBuffer[n:]
+	return n, nil
 }
 
 func (r *BodyReader) Close() error {
@@ -199,37 +238,7 @@ type HijackReader struct {
 }
 
 func (h HijackReader) Read(p []byte) (n int, err error) {
-	// Copy the remaining bytes to the read buffer
-	n0 := copy(p, h.Upstream.NextBuffer)
-	h.Upstream.NextBuffer = h.Upstream.NextBuffer[n0:]
-
-	if len(p) == n0 {
-		// No more bytes to read
-		return n0, nil
-	}
-
-	// Fill The buffer
-	_, err = h.Upstream.Fill()
-	if err != nil {
-		return n0, err
-	}
-
-	// If p is bigger than Uptream.ReadBuffer then read directly from the upstream
-	if len(p)-n0 > len(h.Upstream.ReadBuffer) {
-		n1, err := h.Upstream.R.Read(p[n0:])
-		if err != nil {
-			return n0 + n1, err
-		}
-		return n0 + n1, nil
-	}
-
-	// Read more bytes
-	n1, err := h.Read(p[n0:])
-	if err != nil {
-		return n0 + n1, err
-	}
-
-	return n0 + n1, nil
+	return h.Upstream.read(p)
 }
 
 func (r *RequestReader) Hijack() HijackReader {
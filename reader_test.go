@@ -2,7 +2,10 @@ package h1
 
 import (
 	"bytes"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func Benchmark_Request_Reader(b *testing.B) {
@@ -32,3 +35,34 @@ func Benchmark_Request_Reader(b *testing.B) {
 		}
 	})
 }
+
+func Test_RequestReader_Next_Fragmented(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  string
+		bufSize  int
+		wantPath string
+		wantErr  error
+	}{
+		{"complete", "GET /a HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n\r\n", 8192, "/a", nil},
+		{"truncated", "GET /a HTTP/1.1\r\nHost: exam", 8192, "", io.ErrUnexpectedEOF},
+		{"too large", "GET /a HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n\r\n", 32, "", ErrRequestHeaderTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every Read returns a single byte
+			r := &RequestReader{
+				R:          iotest.OneByteReader(strings.NewReader(tt.request)),
+				ReadBuffer: make([]byte, tt.bufSize),
+			}
+			_, err := r.Next()
+			if err != tt.wantErr {
+				t.Fatalf("Next() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(r.Request.URI.Path()) != tt.wantPath {
+				t.Errorf("path = %q, want %q", r.Request.URI.Path(), tt.wantPath)
+			}
+		})
+	}
+}
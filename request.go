@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
-	"strconv"
+	"math"
 	"sync"
 )
 
@@ -20,7 +20,19 @@ type Request struct {
 	// Parsed URI
 	URI URI
 
+	// Path parameters set by Router
+	Params []Param
+
 	ContentLength int64
+
+	isCookieParsed bool
+	cookies        []CookiePair
+
+	isFormParsed bool
+	isFormMerged bool
+	formBuffer   *[]byte
+	postForm     []Query
+	form         []Query
 }
 
 var requestPool = sync.Pool{
@@ -35,6 +47,10 @@ func (r *Request) Reset() {
 	r.Version = nil
 	r.Headers = r.Headers[:0]
 	r.ContentLength = 0
+	r.isCookieParsed = false
+	r.cookies = r.cookies[:0]
+	r.Params = r.Params[:0]
+	r.resetForm()
 }
 
 func (r *Request) GetHeader(name []byte) (*Header, bool) {
@@ -59,11 +75,14 @@ var ErrInvalidMethod = errors.New("invalid method")
 var ErrInvalidURI = errors.New("invalid uri")
 var ErrInvalidVersion = errors.New("invalid version")
 
+var ErrInvalidHeader = errors.New("invalid header")
+var ErrInvalidContentLength = errors.New("invalid content length")
+
 var ErrBufferTooSmall = errors.New("buffer too small")
 var ErrRequestHeaderTooLarge = errors.New("request header too large")
 
 func splitLine(src []byte) (line, rest []byte, err error) {
-	idx := bytes.IndexByte(src, '\n')
+	idx := indexByte(src, '\n')
 	if idx < 1 { // 0: cr 1: lf
 		return nil, src, ErrBufferTooSmall
 	}
@@ -143,11 +162,11 @@ func ParseRequestLine(dst *Request, src []byte) (next []byte, err error) {
 	if err != nil {
 		return next, err
 	}
-	MethodIndex := bytes.IndexByte(line, ' ')
+	MethodIndex := indexByte(line, ' ')
 	if MethodIndex < 0 || MethodIndex < 3 {
 		return next, ErrInvalidMethod
 	}
-	URIIndex := bytes.IndexByte(line[MethodIndex+1:], ' ')
+	URIIndex := indexByte(line[MethodIndex+1:], ' ')
 	if URIIndex < 0 {
 		return next, ErrInvalidURI
 	}
@@ -175,6 +194,9 @@ func ParseHeaders(dst *Request, src []byte) (next []byte, err error) {
 		}
 		h := Header{}
 		h.Name, h.RawValue = ParseHeaderLine(line)
+		if h.RawValue == nil {
+			return next, ErrInvalidHeader
+		}
 		dst.Headers = append(dst.Headers, h)
 
 		if stricmp(h.Name, ContentLengthHeader) {
@@ -187,32 +209,48 @@ func ParseHeaders(dst *Request, src []byte) (next []byte, err error) {
 	return next, nil
 }
 
+// ParseContentLength parses a Content-Length value, which is 1*DIGIT (RFC 9110 Section 8.6).
+// Signs, spaces and values overflowing an int64 are rejected with ErrInvalidContentLength.
 func ParseContentLength(src []byte) (int64, error) {
-	srcS := bytesToString(src)
-	return strconv.ParseInt(srcS, 10, 64)
+	if len(src) == 0 {
+		return 0, ErrInvalidContentLength
+	}
+	var n int64
+	for _, c := range src {
+		if c < '0' || c > '9' {
+			return 0, ErrInvalidContentLength
+		}
+		if n > (math.MaxInt64-int64(c-'0'))/10 {
+			return 0, ErrInvalidContentLength
+		}
+		n = n*10 + int64(c-'0')
+	}
+	return n, nil
 }
 
+// ParseHeaderLine splits a header line into its name and value.
+// An empty name and a nil value are returned if the line does not start with a token followed by a colon.
 func ParseHeaderLine(src []byte) (name []byte, value []byte) {
-	idx := bytes.IndexByte(src, ':')
-	if idx < 0 {
+	// The name ends at the first non-token byte, which must be the colon
+	idx := indexNonToken(src)
+	if idx == 0 || idx == len(src) || src[idx] != ':' {
 		return src[:0], nil
 	}
 	// RFC2616 Section 4.2
 	// Remove all leading and trailing LWS on field contents
 
-	// skip leading LWS
+	// skip leading LWS (usually a single SP)
 	var i int = idx + 1
-	for ; i < len(src); i++ {
-		if src[i] != ' ' && src[i] != '\t' {
-			break
-		}
+	if i < len(src) && src[i] == ' ' {
+		i++
 	}
-	// skip trailing LWS
+	if i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+		i = skipLWS(src, i, len(src))
+	}
+	// skip trailing LWS (usually none)
 	var j int = len(src) - 1
-	for ; j > i; j-- {
-		if src[j] != ' ' && src[j] != '\t' {
-			break
-		}
+	if j > i && (src[j] == ' ' || src[j] == '\t') {
+		j = skipLWSReverse(src, i, j)
 	}
 	return src[:idx], src[i : j+1]
 }
@@ -243,9 +281,17 @@ func GetBuffer() *[]byte {
 	return bufferPool.Get().(*[]byte)
 }
 
+// Buffers grown beyond this size are not returned to the pool.
+const maxPooledBufferSize = 1 << 20
+
 func PutBuffer(b *[]byte) {
-	if cap(*b) >= BufferPoolSize {
-		bufferPool.Put(b)
+	putBuffer(&bufferPool, b)
+}
+
+func putBuffer(pool *sync.Pool, b *[]byte) {
+	if cap(*b) >= BufferPoolSize && cap(*b) <= maxPooledBufferSize {
+		*b = (*b)[:cap(*b)]
+		pool.Put(b)
 	}
 }
 
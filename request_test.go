@@ -196,6 +196,12 @@ func Test_parseRequestForTestIsValid(t *testing.T) {
 		{"Malformed GET Request1", args{[]byte("GET /HTTP/1.1\r\nHost: localhost\r\n\r\n")}, false},
 		{"Malformed GET Request2", args{[]byte("GET/ HTTP/1.1\r\nHost: localhost\r\n\r\n")}, false},
 		{"Large Request Line", args{LargeHeaderRequest.Bytes()}, true},
+		{"Space Before Colon", args{[]byte("GET / HTTP/1.1\r\nHost : localhost\r\n\r\n")}, false},
+		{"Invalid Header Name", args{[]byte("GET / HTTP/1.1\r\nx(y): z\r\n\r\n")}, false},
+		{"Negative Content-Length", args{[]byte("POST / HTTP/1.1\r\nContent-Length: -1\r\n\r\n")}, false},
+		{"Empty Header Name", args{[]byte("GET / HTTP/1.1\r\n: z\r\n\r\n")}, false},
+		{"Missing Colon", args{[]byte("GET / HTTP/1.1\r\nHost localhost\r\n\r\n")}, false},
+		{"Folded Header", args{[]byte("GET / HTTP/1.1\r\nX-A: a\r\n b\r\n\r\n")}, false},
 		{"Real Chrome Request", args{[]byte("GET / HTTP/1.1\r\nHost: localhost:8080\r\nConnection: keep-alive\r\nCache-Control: max-age=0\r\nsec-ch-ua: \" Not;A Brand\";v=\"99\", \"Google Chrome\";v=\"97\", \"Chromium\";v=\"97\"\r\nsec-ch-ua-mobile: ?0\r\nsec-ch-ua-platform: \"Windows\"\r\nUpgrade-Insecure-Requests: 1\r\nDNT: 1\r\nUser-Agent: Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/97.0.4692.99 Safari/537.36\r\nAccept: text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9\r\nSec-Fetch-Site: cross-site\r\nSec-Fetch-Mode: navigate\r\nSec-Fetch-User: ?1\r\nSec-Fetch-Dest: document\r\nAccept-Encoding: gzip, deflate, br\r\nAccept-Language: ko,ko-KR;q=0.9,en-US;q=0.8,en;q=0.7\r\n\r\n")}, true},
 	}
 	for _, tt := range tests {
@@ -227,3 +233,30 @@ func BenchmarkParseRequest(b *testing.B) {
 		}
 	})
 }
+
+func Test_ParseContentLength(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"12", 12, false},
+		{"007", 7, false},
+		{"9223372036854775807", 9223372036854775807, false},
+		{"9223372036854775808", 0, true},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"+5", 0, true},
+		{" 5", 0, true},
+		{"5 ", 0, true},
+		{"1,2", 0, true},
+		{"0x10", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseContentLength([]byte(tt.src))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseContentLength(%q) = %d, %v, want %d, error %v", tt.src, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -8,26 +8,37 @@ import (
 
 var ResponsePool = sync.Pool{
 	New: func() any {
-		return &Response{
-			upstream:      nil,
-			buf:           make([]byte, 0, 8192),
-			itoaBuf:       make([]byte, 0, 32),
-			n:             0,
-			ContentLength: -1,
-			//Connection:    ConnectionKeepAlive,
-		}
+		return newResponse(nil)
 	},
 }
 
+func newResponse(pool *sync.Pool) *Response {
+	return &Response{
+		upstream:      nil,
+		buf:           make([]byte, 0, 8192),
+		itoaBuf:       make([]byte, 0, 32),
+		n:             0,
+		ContentLength: -1,
+		//Connection:    ConnectionKeepAlive,
+		pool: pool,
+	}
+}
+
 func GetResponse(upstream io.Writer) *Response {
 	r := ResponsePool.Get().(*Response)
 	r.upstream = upstream
 	return r
 }
 
+// PutResponse returns r to the pool it was taken from.
 func PutResponse(r *Response) {
 	r.Reset()
 	r.upstream = nil
+	r.DateServerHeaderFunc = nil
+	if r.pool != nil {
+		r.pool.Put(r)
+		return
+	}
 	ResponsePool.Put(r)
 }
 
@@ -40,34 +51,78 @@ type Response struct {
 	// Itoa Buffer
 	itoaBuf []byte // buffer for itoa
 
+	// Cookie Expires cache
+	expiresUnix int64
+	expiresBuf  []byte
+
+	// multipart/byteranges boundary
+	boundaryRaw [12]byte
+	boundary    [24]byte
+
 	// Standard Hop-by-Hop response headers.
-	ContentLength int
+	ContentLength int64
+	Chunked       bool // Transfer-Encoding: chunked (only used if ContentLength < 0)
 	//Connection    Connection
+
+	// DateServerHeaderFunc returns the Date and Server header lines written by WriteHeader
+	// (DefaultFastDateServer.GetDate if nil). It is set by Server and not cleared by Reset.
+	DateServerHeaderFunc func() []byte
+
+	// OmitDateServerHeader leaves the Date and Server headers out of the next WriteHeader.
+	OmitDateServerHeader bool
+
+	// NoBody discards everything written after EndHeader, as required for responses to HEAD.
+	NoBody bool
+
+	discardBody bool
+
+	// Header lines added with AddHeader
+	extraHeaders []byte
+
+	// Bytes written to upstream
+	sent int64
+
+	// Pool of a Server accept loop (ResponsePool if nil)
+	pool *sync.Pool
 }
 
 func (r *Response) Reset() {
 	r.n = 0
 	r.buf = r.buf[:0]
+	r.resetHeader()
+}
+
+// resetHeader clears the settings of the previous response, keeping its buffered output.
+func (r *Response) resetHeader() {
 	r.ContentLength = -1
+	r.Chunked = false
+	r.OmitDateServerHeader = false
+	r.NoBody = false
+	r.discardBody = false
+	r.extraHeaders = r.extraHeaders[:0]
 }
 
+// DefaultFastDateServer provides the Date and Server headers of responses without a DateServerHeaderFunc.
+// It is started lazily by the first response.
 var DefaultFastDateServer = NewFastDateServer("h1")
 
-var _ = func() int {
-	go DefaultFastDateServer.Start()
-	return 0
-}()
-
-var DateServerHeaderFunc = func() []byte {
+func (r *Response) dateServerHeader() []byte {
+	if r.OmitDateServerHeader {
+		return nil
+	}
+	if r.DateServerHeaderFunc != nil {
+		return r.DateServerHeaderFunc()
+	}
 	return DefaultFastDateServer.GetDate()
 }
 
+// Flush writes the buffered output to the connection and resets the Response for the next response.
 func (r *Response) Flush() error {
 	if r.upstream == nil || r.n == 0 {
 		return nil
 	}
 
-	_, err := r.upstream.Write(r.buf[:r.n])
+	err := r.flushBuffer()
 	if err != nil {
 		return err
 	}
@@ -76,54 +131,76 @@ func (r *Response) Flush() error {
 	return nil
 }
 
+// flushBuffer writes the buffered output to the connection in the middle of a response, keeping its state.
+func (r *Response) flushBuffer() error {
+	if r.upstream == nil || r.n == 0 {
+		return nil
+	}
+
+	n, err := r.upstream.Write(r.buf[:r.n])
+	r.sent += int64(n)
+	if err != nil {
+		return err
+	}
+
+	r.n = 0
+	return nil
+}
+
 func (r *Response) Write(b []byte) (int, error) {
+	if r.discardBody {
+		return len(b), nil
+	}
 	n := copy(r.buf[r.n:cap(r.buf)], b) // copy to buffer
 	r.n += n
-	if len(r.buf) < cap(r.buf) {
+	if n == len(b) {
 		return n, nil
 	}
 
 	// buffer is full, flush it
-	err := r.Flush()
+	err := r.flushBuffer()
 	if err != nil {
-		return 0, err
+		return n, err
 	}
 
-	// If b is bigger than buffer, write it directly
+	// If the rest of b is bigger than buffer, write it directly
 	if len(b)-n > cap(r.buf) {
-		_, err = r.upstream.Write(b[n:])
+		m, err := r.upstream.Write(b[n:])
+		r.sent += int64(m)
 		return len(b), err
 	}
 
-	// copy b to buffer
-	copy(r.buf, b[n:])
-	r.n = len(b) - n
-	return n, nil
+	// copy the rest of b to buffer
+	r.n = copy(r.buf[:cap(r.buf)], b[n:])
+	return len(b), nil
 }
 
 func (r *Response) WriteString(b string) (int, error) {
+	if r.discardBody {
+		return len(b), nil
+	}
 	n := copy(r.buf[r.n:cap(r.buf)], b) // copy to buffer
 	r.n += n
-	if len(r.buf) < cap(r.buf) {
+	if n == len(b) {
 		return n, nil
 	}
 
 	// buffer is full, flush it
-	err := r.Flush()
+	err := r.flushBuffer()
 	if err != nil {
-		return 0, err
+		return n, err
 	}
 
-	// If b is bigger than buffer, write it directly
+	// If the rest of b is bigger than buffer, write it directly
 	if len(b)-n > cap(r.buf) {
-		_, err = r.upstream.Write(stringToBytes(b[n:]))
+		m, err := r.upstream.Write(stringToBytes(b[n:]))
+		r.sent += int64(m)
 		return len(b), err
 	}
 
-	// copy b to buffer
-	copy(r.buf, b[n:])
-	r.n = len(b) - n
-	return n, nil
+	// copy the rest of b to buffer
+	r.n = copy(r.buf[:cap(r.buf)], b[n:])
+	return len(b), nil
 }
 
 func (r *Response) WriteInt(i int) (int, error) {
@@ -162,6 +239,7 @@ func (r *Response) WriteStatusLine(status int) error {
 }
 
 var contentLengthHeader = []byte("Content-Length: ")
+var transferEncodingChunkedHeader = []byte("Transfer-Encoding: chunked\r\n")
 var crlf = []byte("\r\n")
 
 func (r *Response) WriteHeader(status int) error {
@@ -171,18 +249,42 @@ func (r *Response) WriteHeader(status int) error {
 	}
 	// Write standard hop-by-hop response headers
 
-	_, err = r.Write(DateServerHeaderFunc())
+	err = r.writeDefaultHeaders()
 	if err != nil {
 		return err
 	}
 
+	return r.writeFraming()
+}
+
+// AddHeader adds a header line to be written by the next WriteHeader.
+// It lets middleware set headers before the handler writes the status line.
+func (r *Response) AddHeader(name, value []byte) {
+	r.extraHeaders = append(r.extraHeaders, name...)
+	r.extraHeaders = append(r.extraHeaders, ": "...)
+	r.extraHeaders = append(r.extraHeaders, value...)
+	r.extraHeaders = append(r.extraHeaders, "\r\n"...)
+}
+
+// writeDefaultHeaders writes the Date and Server headers and the headers added with AddHeader.
+func (r *Response) writeDefaultHeaders() error {
+	_, err := r.Write(r.dateServerHeader())
+	if err != nil {
+		return err
+	}
+	_, err = r.Write(r.extraHeaders)
+	return err
+}
+
+// writeFraming writes the Content-Length or Transfer-Encoding header.
+func (r *Response) writeFraming() error {
 	// Content-Length
 	if r.ContentLength >= 0 {
-		_, err = r.Write(contentLengthHeader)
+		_, err := r.Write(contentLengthHeader)
 		if err != nil {
 			return err
 		}
-		_, err = r.WriteInt(r.ContentLength)
+		_, err = r.WriteInt64(r.ContentLength)
 		if err != nil {
 			return err
 		}
@@ -190,7 +292,50 @@ func (r *Response) WriteHeader(status int) error {
 		if err != nil {
 			return err
 		}
+		return nil
+	}
+
+	// Transfer-Encoding
+	if r.Chunked {
+		_, err := r.Write(transferEncodingChunkedHeader)
+		return err
 	}
 
 	return nil
 }
+
+// EndHeader writes the empty line that ends the header section.
+func (r *Response) EndHeader() error {
+	_, err := r.Write(crlf)
+	r.discardBody = r.NoBody
+	return err
+}
+
+// WriteChunk writes b as a single chunk of a chunked body. Empty chunks are skipped.
+func (r *Response) WriteChunk(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	_, err := r.WriteUint64Hex(uint64(len(b)))
+	if err != nil {
+		return 0, err
+	}
+	_, err = r.Write(crlf)
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.Write(b)
+	if err != nil {
+		return n, err
+	}
+	_, err = r.Write(crlf)
+	return n, err
+}
+
+var lastChunk = []byte("0\r\n\r\n")
+
+// WriteLastChunk ends a chunked body.
+func (r *Response) WriteLastChunk() error {
+	_, err := r.Write(lastChunk)
+	return err
+}
@@ -0,0 +1,6 @@
+//go:build linux && (386 || amd64 || arm)
+
+package h1
+
+// soReusePort is SO_REUSEPORT, which the frozen syscall package does not define on these ports.
+const soReusePort = 0xf
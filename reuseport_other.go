@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package h1
+
+import (
+	"net"
+)
+
+func listenReusePort(addr string, n int) ([]net.Listener, error) {
+	return nil, ErrReusePortUnsupported
+}
@@ -0,0 +1,7 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd || (linux && !386 && !amd64 && !arm)
+
+package h1
+
+import "syscall"
+
+const soReusePort = syscall.SO_REUSEPORT
@@ -0,0 +1,148 @@
+package h1
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+)
+
+func Test_Server_ServeListeners(t *testing.T) {
+	lns, err := listenReusePort("127.0.0.1:0", 4)
+	if err != nil {
+		t.Skip(err)
+	}
+	addr := lns[0].Addr().String()
+	for _, ln := range lns[1:] {
+		if ln.Addr().String() != addr {
+			t.Fatalf("listener address = %s, want %s", ln.Addr(), addr)
+		}
+	}
+
+	s := &Server{Handler: helloHandler}
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ServeListeners(lns)
+	}()
+
+	var wg sync.WaitGroup
+	for c := 0; c < 20; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+			for i := 0; i < 3; i++ {
+				path := fmt.Sprintf("/%d/%d", c, i)
+				body, err := netpollGet(conn, br, path)
+				if err != nil || body != path {
+					t.Errorf("GET %s = %q, %v", path, body, err)
+					return
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("ServeListeners() error = %v, want %v", err, ErrServerClosed)
+	}
+}
+
+func Test_ReusePort_BothListenersServe(t *testing.T) {
+	lns, err := listenReusePort("127.0.0.1:0", 2)
+	if err != nil {
+		t.Skip(err)
+	}
+	addr := lns[0].Addr().String()
+
+	// Each listener has its own Server, whose handler answers with the listener index
+	for i, ln := range lns {
+		name := []byte(fmt.Sprint(i))
+		s := &Server{Handler: func(resp *Response, req *RequestReader) error {
+			resp.ContentLength = int64(len(name))
+			resp.WriteHeader(200)
+			resp.EndHeader()
+			_, err := resp.Write(name)
+			return err
+		}}
+		go s.Serve(ln)
+		defer s.Shutdown(context.Background())
+	}
+
+	// The kernel picks a listener per connection, dial until both have answered
+	served := map[string]bool{}
+	for c := 0; c < 500 && len(served) < len(lns); c++ {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := netpollGet(conn, bufio.NewReader(conn), "/")
+		conn.Close()
+		if err != nil {
+			t.Fatalf("GET / error = %v", err)
+		}
+		served[body] = true
+	}
+	if !served["0"] || !served["1"] {
+		t.Errorf("served by listeners %v, want both", served)
+	}
+}
+
+func Benchmark_Server_Listeners(b *testing.B) {
+	b.Run("Single", func(b *testing.B) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			b.Skip(err)
+		}
+		s := &Server{Handler: helloHandler}
+		go s.Serve(ln)
+		benchmarkServer(b, s, ln.Addr().String())
+	})
+	b.Run("ReusePort", func(b *testing.B) {
+		lns, err := listenReusePort("127.0.0.1:0", 4)
+		if err != nil {
+			b.Skip(err)
+		}
+		s := &Server{Handler: helloHandler}
+		go s.ServeListeners(lns)
+		benchmarkServer(b, s, lns[0].Addr().String())
+	})
+}
+
+// benchmarkServer sends every request to addr over a new connection, so that the accept loops are measured.
+func benchmarkServer(b *testing.B, s *Server, addr string) {
+	defer s.Shutdown(context.Background())
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			// The server closes first, so the client ports are not left in TIME_WAIT
+			_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: close\r\n\r\n")
+			if err == nil {
+				_, err = io.Copy(io.Discard, conn)
+			}
+			conn.Close()
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package h1
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+var reusePortConfig = net.ListenConfig{
+	Control: func(network, address string, c syscall.RawConn) error {
+		var err error
+		cerr := c.Control(func(fd uintptr) {
+			err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+		})
+		if cerr != nil {
+			return cerr
+		}
+		return err
+	},
+}
+
+// listenReusePort opens n SO_REUSEPORT listeners on the TCP address addr.
+func listenReusePort(addr string, n int) ([]net.Listener, error) {
+	lns := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		ln, err := reusePortConfig.Listen(context.Background(), "tcp", addr)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, err
+		}
+		// The other listeners bind the port picked for ":0"
+		if i == 0 {
+			addr = ln.Addr().String()
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
@@ -0,0 +1,335 @@
+package h1
+
+import (
+	"strings"
+)
+
+// Param is a path parameter matched by a Router.
+type Param struct {
+	Name  string
+	Value []byte // sub-slice of the decoded path
+}
+
+// Param returns the value of the path parameter name matched by a Router, or nil.
+func (r *Request) Param(name string) []byte {
+	for i := range r.Params {
+		if r.Params[i].Name == name {
+			return r.Params[i].Value
+		}
+	}
+	return nil
+}
+
+// TrailingSlash selects how a Router answers a path that only matches a route with (or without) a trailing slash.
+type TrailingSlash uint8
+
+const (
+	TrailingSlashRedirect TrailingSlash = iota // redirect to the route (301, or 308 for methods other than GET and HEAD)
+	TrailingSlashStrict                        // 404 Not Found
+	TrailingSlashIgnore                        // serve the route
+)
+
+const methodCount = int(MethodBREW) + 1
+
+// Router dispatches requests on their method and decoded path using a radix tree.
+//
+// Patterns are made of static segments, named parameters (":name", up to the next '/')
+// and a final catch-all parameter ("*name", the rest of the path, possibly empty).
+// Static segments take precedence over parameters, parameters over catch-alls.
+//
+// Requests for a known path with an unregistered method get 405 Method Not Allowed with an Allow header.
+// HEAD is served by the GET handler without a body and OPTIONS is answered with the allowed methods,
+// unless handlers are registered for them. The zero value is an empty Router.
+type Router struct {
+	// TrailingSlash is the policy for paths differing from a route by a trailing slash
+	TrailingSlash TrailingSlash
+
+	// NotFound is called for unmatched paths (404 Not Found if nil)
+	NotFound Handler
+
+	root     node
+	methods  [methodCount]bool
+	allowAll []byte // Allow header for "OPTIONS *"
+}
+
+type node struct {
+	prefix   string
+	indices  []byte // first bytes of the static children
+	children []*node
+	param    *node // ":name" child
+	catchAll *node // "*name" child
+	name     string
+
+	handlers [methodCount]Handler
+	allow    []byte // Allow header line, nil if no handler is registered
+}
+
+func (rt *Router) GET(pattern string, h Handler)    { rt.Handle(MethodGET, pattern, h) }
+func (rt *Router) POST(pattern string, h Handler)   { rt.Handle(MethodPOST, pattern, h) }
+func (rt *Router) PUT(pattern string, h Handler)    { rt.Handle(MethodPUT, pattern, h) }
+func (rt *Router) PATCH(pattern string, h Handler)  { rt.Handle(MethodPATCH, pattern, h) }
+func (rt *Router) DELETE(pattern string, h Handler) { rt.Handle(MethodDELETE, pattern, h) }
+
+// Handle registers h for requests with method matching pattern.
+// It panics if the pattern is invalid or conflicts with a registered route.
+func (rt *Router) Handle(method Method, pattern string, h Handler) {
+	if method == MethodInvalid || int(method) >= methodCount {
+		panic("h1: invalid method for route " + pattern)
+	}
+	if h == nil {
+		panic("h1: nil handler for route " + pattern)
+	}
+	if len(pattern) == 0 || pattern[0] != '/' {
+		panic("h1: route must begin with '/': " + pattern)
+	}
+
+	n := &rt.root
+	rest := pattern
+	for len(rest) > 0 {
+		i := strings.IndexAny(rest, ":*")
+		if i == -1 {
+			n = n.insertStatic(rest)
+			break
+		}
+		if rest[i-1] != '/' {
+			panic("h1: parameter must begin a segment in route " + pattern)
+		}
+		n = n.insertStatic(rest[:i])
+
+		end := strings.IndexByte(rest[i:], '/')
+		if end == -1 {
+			end = len(rest)
+		} else {
+			end += i
+		}
+		name := rest[i+1 : end]
+		if len(name) == 0 || strings.ContainsAny(name, ":*") {
+			panic("h1: invalid parameter name in route " + pattern)
+		}
+
+		if rest[i] == ':' {
+			if n.param == nil {
+				n.param = &node{name: name}
+			} else if n.param.name != name {
+				panic("h1: parameter :" + name + " conflicts with :" + n.param.name + " in route " + pattern)
+			}
+			n = n.param
+		} else {
+			if end != len(rest) {
+				panic("h1: catch-all parameter must be last in route " + pattern)
+			}
+			if n.catchAll == nil {
+				n.catchAll = &node{name: name}
+			} else if n.catchAll.name != name {
+				panic("h1: parameter *" + name + " conflicts with *" + n.catchAll.name + " in route " + pattern)
+			}
+			n = n.catchAll
+		}
+		rest = rest[end:]
+	}
+
+	if n.handlers[method] != nil {
+		panic("h1: duplicate route " + method.String() + " " + pattern)
+	}
+	n.handlers[method] = h
+	n.allow = appendAllowHeader(nil, &n.handlers)
+
+	rt.methods[method] = true
+	var all [methodCount]Handler
+	for m := range rt.methods {
+		if rt.methods[m] {
+			all[m] = h
+		}
+	}
+	rt.allowAll = appendAllowHeader(nil, &all)
+}
+
+// insertStatic adds the static path s below n, splitting existing children on their common prefix.
+func (n *node) insertStatic(s string) *node {
+	for len(s) > 0 {
+		i := indexByte(n.indices, s[0])
+		if i == -1 {
+			child := &node{prefix: s}
+			n.indices = append(n.indices, s[0])
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+		l := 0
+		for l < len(s) && l < len(child.prefix) && s[l] == child.prefix[l] {
+			l++
+		}
+		if l < len(child.prefix) {
+			split := *child
+			split.prefix = child.prefix[l:]
+			*child = node{
+				prefix:   child.prefix[:l],
+				indices:  []byte{split.prefix[0]},
+				children: []*node{&split},
+			}
+		}
+		n = child
+		s = s[l:]
+	}
+	return n
+}
+
+// lookup matches path (the part after the prefix of n) and appends the parameters to params.
+func (n *node) lookup(path []byte, params *[]Param) *node {
+	if len(path) == 0 && n.allow != nil {
+		return n
+	}
+
+	if len(path) > 0 {
+		if i := indexByte(n.indices, path[0]); i != -1 {
+			child := n.children[i]
+			if len(path) >= len(child.prefix) && string(path[:len(child.prefix)]) == child.prefix {
+				if match := child.lookup(path[len(child.prefix):], params); match != nil {
+					return match
+				}
+			}
+		}
+	}
+
+	if n.param != nil {
+		end := indexByte(path, '/')
+		if end == -1 {
+			end = len(path)
+		}
+		if end > 0 {
+			*params = append(*params, Param{Name: n.param.name, Value: path[:end]})
+			if match := n.param.lookup(path[end:], params); match != nil {
+				return match
+			}
+			*params = (*params)[:len(*params)-1]
+		}
+	}
+
+	if n.catchAll != nil {
+		*params = append(*params, Param{Name: n.catchAll.name, Value: path})
+		return n.catchAll
+	}
+
+	return nil
+}
+
+var allowHeader = []byte("Allow: ")
+
+// appendAllowHeader appends the Allow header line listing the methods with handlers,
+// HEAD if GET is handled, and OPTIONS.
+func appendAllowHeader(dst []byte, handlers *[methodCount]Handler) []byte {
+	dst = append(dst, allowHeader...)
+	for m := 1; m < methodCount; m++ {
+		method := Method(m)
+		if handlers[m] == nil && !(method == MethodHEAD && handlers[MethodGET] != nil) && method != MethodOPTIONS {
+			continue
+		}
+		if len(dst) > len(allowHeader) {
+			dst = append(dst, ", "...)
+		}
+		dst = append(dst, method.String()...)
+	}
+	return append(dst, "\r\n"...)
+}
+
+// Serve dispatches the request to the handler of the matching route.
+func (rt *Router) Serve(resp *Response, req *RequestReader) error {
+	r := &req.Request
+	path := r.URI.Path()
+
+	if r.Method == MethodOPTIONS && string(path) == "*" {
+		return writeAllow(resp, rt.allowAll)
+	}
+
+	r.Params = r.Params[:0]
+	n := rt.root.lookup(path, &r.Params)
+	if n == nil {
+		r.Params = r.Params[:0]
+		return rt.serveTrailingSlash(resp, req, path)
+	}
+	return rt.serveNode(resp, req, n)
+}
+
+func (rt *Router) serveNode(resp *Response, req *RequestReader, n *node) error {
+	r := &req.Request
+	if h := n.handlers[r.Method]; h != nil {
+		return h(resp, req)
+	}
+
+	switch r.Method {
+	case MethodHEAD:
+		if h := n.handlers[MethodGET]; h != nil {
+			resp.NoBody = true
+			return h(resp, req)
+		}
+	case MethodOPTIONS:
+		return writeAllow(resp, n.allow)
+	}
+	return writeStatusText(resp, r, 405, n.allow)
+}
+
+// serveTrailingSlash looks up path with the trailing slash added or removed and applies the TrailingSlash policy.
+func (rt *Router) serveTrailingSlash(resp *Response, req *RequestReader, path []byte) error {
+	r := &req.Request
+	raw := r.URI.RawPath
+	if rt.TrailingSlash == TrailingSlashStrict || len(path) <= 1 || len(raw) == 0 ||
+		// An escaped slash cannot be removed from the raw path
+		(path[len(path)-1] == '/') != (raw[len(raw)-1] == '/') {
+		return rt.notFound(resp, req)
+	}
+
+	buffer := GetBuffer()
+	defer PutBuffer(buffer)
+
+	hasSlash := path[len(path)-1] == '/'
+	if hasSlash {
+		*buffer = append((*buffer)[:0], path[:len(path)-1]...)
+	} else {
+		*buffer = append(append((*buffer)[:0], path...), '/')
+	}
+
+	n := rt.root.lookup(*buffer, &r.Params)
+	if n == nil {
+		r.Params = r.Params[:0]
+		return rt.notFound(resp, req)
+	}
+	if rt.TrailingSlash == TrailingSlashIgnore {
+		// The parameters point into buffer
+		err := rt.serveNode(resp, req, n)
+		r.Params = r.Params[:0]
+		return err
+	}
+	r.Params = r.Params[:0]
+
+	status := 308
+	if r.Method == MethodGET || r.Method == MethodHEAD {
+		status = 301
+	}
+	resp.ContentLength = 0
+	err := resp.WriteHeader(status)
+	if err != nil {
+		return err
+	}
+	writeSlashLocation(resp, r, !hasSlash)
+	return resp.EndHeader()
+}
+
+func (rt *Router) notFound(resp *Response, req *RequestReader) error {
+	if rt.NotFound != nil {
+		return rt.NotFound(resp, req)
+	}
+	return writeStatusText(resp, &req.Request, 404, nil)
+}
+
+// writeAllow answers OPTIONS with 204 No Content and the Allow header line allow.
+func writeAllow(resp *Response, allow []byte) error {
+	resp.ContentLength = -1
+	resp.Chunked = false
+	err := resp.WriteHeader(204)
+	if err != nil {
+		return err
+	}
+	resp.Write(allow)
+	return resp.EndHeader()
+}
@@ -0,0 +1,168 @@
+package h1
+
+import (
+	"strings"
+	"testing"
+)
+
+// routeHandler writes the route name and the matched parameters.
+func routeHandler(name string) Handler {
+	return func(resp *Response, req *RequestReader) error {
+		var body strings.Builder
+		body.WriteString(name)
+		for _, p := range req.Request.Params {
+			body.WriteString(" " + p.Name + "=" + string(p.Value))
+		}
+		resp.ContentLength = int64(body.Len())
+		resp.WriteHeader(200)
+		resp.EndHeader()
+		_, err := resp.WriteString(body.String())
+		return err
+	}
+}
+
+func newTestRouter() *Router {
+	rt := &Router{}
+	rt.GET("/", routeHandler("root"))
+	rt.GET("/users", routeHandler("users"))
+	rt.POST("/users", routeHandler("create"))
+	rt.GET("/users/new", routeHandler("new"))
+	rt.GET("/users/:id", routeHandler("user"))
+	rt.DELETE("/users/:id", routeHandler("delete"))
+	rt.GET("/users/:id/posts/:post", routeHandler("post"))
+	rt.GET("/static/*file", routeHandler("static"))
+	rt.GET("/docs/", routeHandler("docs"))
+	rt.GET("//docs/", routeHandler("docs"))
+	rt.GET("/search", routeHandler("search"))
+	rt.Handle(MethodOPTIONS, "/cors", routeHandler("cors"))
+	return rt
+}
+
+func Test_Router(t *testing.T) {
+	rt := newTestRouter()
+
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{"Root", "GET / HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 4\r\n\r\nroot"},
+		{"Static", "GET /users HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nusers"},
+		{"Method", "POST /users HTTP/1.1\r\nContent-Length: 0\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 6\r\n\r\ncreate"},
+		{"Static Over Param", "GET /users/new HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\nnew"},
+		{"Param", "GET /users/42 HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nuser id=42"},
+		{"Param Prefix Of Static", "GET /users/ne HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nuser id=ne"},
+		{"Params", "GET /users/7/posts/hello HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 20\r\n\r\npost id=7 post=hello"},
+		{"Decoded Param", "GET /users/a%20b HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 11\r\n\r\nuser id=a b"},
+		{"Catch-All", "GET /static/css/site.css HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 24\r\n\r\nstatic file=css/site.css"},
+		{"Empty Catch-All", "GET /static/ HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 12\r\n\r\nstatic file="},
+		{"Not Found", "GET /missing HTTP/1.1\r\n\r\n", "HTTP/1.1 404 Not Found\r\nContent-Length: 13\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n404 Not Found"},
+		{"Partial Static", "GET /user HTTP/1.1\r\n\r\n", "HTTP/1.1 404 Not Found\r\nContent-Length: 13\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n404 Not Found"},
+		{"Method Not Allowed", "PUT /users/42 HTTP/1.1\r\nContent-Length: 0\r\n\r\n", "HTTP/1.1 405 Method Not Allowed\r\nContent-Length: 22\r\nContent-Type: text/plain; charset=utf-8\r\nAllow: GET, HEAD, DELETE, OPTIONS\r\n\r\n405 Method Not Allowed"},
+		{"HEAD", "HEAD /users/42 HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\n"},
+		{"OPTIONS", "OPTIONS /users HTTP/1.1\r\n\r\n", "HTTP/1.1 204 No Content\r\nAllow: GET, HEAD, POST, OPTIONS\r\n\r\n"},
+		{"OPTIONS Handler", "OPTIONS /cors HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 4\r\n\r\ncors"},
+		{"OPTIONS Server", "OPTIONS * HTTP/1.1\r\n\r\n", "HTTP/1.1 204 No Content\r\nAllow: GET, HEAD, POST, DELETE, OPTIONS\r\n\r\n"},
+		{"Add Slash", "GET /docs?q=1 HTTP/1.1\r\n\r\n", "HTTP/1.1 301 Moved Permanently\r\nContent-Length: 0\r\nLocation: docs/?q=1\r\n\r\n"},
+		{"Remove Slash", "GET /search/ HTTP/1.1\r\n\r\n", "HTTP/1.1 301 Moved Permanently\r\nContent-Length: 0\r\nLocation: ../search\r\n\r\n"},
+		{"Redirect POST", "POST /users/ HTTP/1.1\r\nContent-Length: 0\r\n\r\n", "HTTP/1.1 308 Permanent Redirect\r\nContent-Length: 0\r\nLocation: ../users\r\n\r\n"},
+		{"Double Slash", "GET //docs HTTP/1.1\r\n\r\n", "HTTP/1.1 301 Moved Permanently\r\nContent-Length: 0\r\nLocation: docs/\r\n\r\n"},
+		{"Catch-All Slash", "GET /static HTTP/1.1\r\n\r\n", "HTTP/1.1 301 Moved Permanently\r\nContent-Length: 0\r\nLocation: static/\r\n\r\n"},
+		{"Escaped Slash", "GET /search%2F HTTP/1.1\r\n\r\n", "HTTP/1.1 404 Not Found\r\nContent-Length: 13\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n404 Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveTestRequest(t, rt.Serve, tt.request); got != tt.want {
+				t.Errorf("Serve() wrote\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Router_TrailingSlash(t *testing.T) {
+	notFound := "HTTP/1.1 404 Not Found\r\nContent-Length: 13\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n404 Not Found"
+
+	tests := []struct {
+		name    string
+		policy  TrailingSlash
+		request string
+		want    string
+	}{
+		{"Strict Add", TrailingSlashStrict, "GET /docs HTTP/1.1\r\n\r\n", notFound},
+		{"Strict Remove", TrailingSlashStrict, "GET /users/42/ HTTP/1.1\r\n\r\n", notFound},
+		{"Ignore Add", TrailingSlashIgnore, "GET /docs HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 4\r\n\r\ndocs"},
+		{"Ignore Remove", TrailingSlashIgnore, "GET /users/42/ HTTP/1.1\r\n\r\n", "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nuser id=42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := newTestRouter()
+			rt.TrailingSlash = tt.policy
+			if got := serveTestRequest(t, rt.Serve, tt.request); got != tt.want {
+				t.Errorf("Serve() wrote\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Router_NotFound(t *testing.T) {
+	rt := newTestRouter()
+	rt.NotFound = routeHandler("fallback")
+
+	want := "HTTP/1.1 200 OK\r\nContent-Length: 8\r\n\r\nfallback"
+	if got := serveTestRequest(t, rt.Serve, "GET /nowhere HTTP/1.1\r\n\r\n"); got != want {
+		t.Errorf("Serve() wrote %q, want %q", got, want)
+	}
+}
+
+func Test_Router_Handle_Panics(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"No Slash", "users"},
+		{"Duplicate", "/users"},
+		{"Param Conflict", "/users/:name"},
+		{"Catch-All Not Last", "/files/*path/x"},
+		{"Empty Name", "/a/:"},
+		{"Mid-Segment", "/a:b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := newTestRouter()
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Handle(%q) did not panic", tt.pattern)
+				}
+			}()
+			rt.GET(tt.pattern, routeHandler("x"))
+		})
+	}
+}
+
+func Test_Router_Allocs(t *testing.T) {
+	rt := &Router{}
+	rt.GET("/users/:id/posts/:post", func(resp *Response, req *RequestReader) error {
+		return nil
+	})
+
+	reader := newTestRequestReader("GET /users/7/posts/hello HTTP/1.1\r\n\r\n")
+	if _, err := reader.Next(); err != nil {
+		t.Fatal(err)
+	}
+	resp := GetResponse(nil)
+	defer PutResponse(resp)
+
+	rt.Serve(resp, reader)
+	allocs := testing.AllocsPerRun(100, func() {
+		rt.Serve(resp, reader)
+	})
+	if allocs != 0 {
+		t.Errorf("Serve() allocates %v times", allocs)
+	}
+	if string(reader.Request.Param("post")) != "hello" {
+		t.Errorf("Param(post) = %q", reader.Request.Param("post"))
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !purego
+
+package h1
+
+import "bytes"
+
+// indexByte returns the index of the first c in b, or -1.
+// In the default build it calls bytes.IndexByte, which is implemented with SIMD instructions on most platforms;
+// only the purego build uses the SWAR scanner (swarIndexByte).
+func indexByte(b []byte, c byte) int {
+	return bytes.IndexByte(b, c)
+}
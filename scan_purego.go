@@ -0,0 +1,8 @@
+//go:build purego
+
+package h1
+
+// indexByte returns the index of the first c in b, or -1.
+func indexByte(b []byte, c byte) int {
+	return swarIndexByte(b, c)
+}
@@ -0,0 +1,96 @@
+package h1
+
+import (
+	"io"
+	"net"
+	"os"
+)
+
+// SendFile flushes the buffered response and writes length bytes of f starting at offset.
+// A negative length sends the rest of the file.
+//
+// If the upstream is a *net.TCPConn, the body is handed to its ReadFrom,
+// which uses sendfile/splice on Linux and never touches the response buffer.
+// Any other writer falls back to copying through the response buffer.
+func (r *Response) SendFile(f *os.File, offset, length int64) (int64, error) {
+	if length < 0 {
+		st, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		length = st.Size() - offset
+		if length < 0 {
+			length = 0
+		}
+	}
+
+	if r.discardBody {
+		return length, nil
+	}
+
+	// Headers must reach the connection before the file body
+	err := r.flushBuffer()
+	if err != nil {
+		return 0, err
+	}
+	if length == 0 {
+		return 0, nil
+	}
+
+	if upstream, ok := r.upstream.(*net.TCPConn); ok {
+		n, err := sendFileReaderFrom(upstream, f, offset, length)
+		r.sent += n
+		return n, err
+	}
+
+	return r.copyReaderAt(f, offset, length)
+}
+
+// sendFileReaderFrom seeks f to offset and passes it to dst as an *io.LimitedReader,
+// the only shape the net package recognises for sendfile.
+func sendFileReaderFrom(dst io.ReaderFrom, f *os.File, offset, length int64) (int64, error) {
+	_, err := f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := dst.ReadFrom(&io.LimitedReader{R: f, N: length})
+	if err == nil && n < length {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// copyReaderAt reads length bytes of src at offset directly into the response buffer, flushing whenever it fills up.
+// The tail is left in the buffer like any other Write.
+func (r *Response) copyReaderAt(src io.ReaderAt, offset, length int64) (int64, error) {
+	if r.discardBody {
+		return length, nil
+	}
+	section := io.NewSectionReader(src, offset, length)
+
+	var n int64
+	for n < length {
+		if r.n == cap(r.buf) {
+			err := r.flushBuffer()
+			if err != nil {
+				return n, err
+			}
+		}
+
+		m, err := section.Read(r.buf[r.n:cap(r.buf)])
+		r.n += m
+		n += int64(m)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if n < length {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
@@ -0,0 +1,121 @@
+package h1
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newSendFileTestFile(t *testing.T, size int) (*os.File, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*7 + i>>8)
+	}
+	name := filepath.Join(t.TempDir(), "sendfile.bin")
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f, data
+}
+
+var sendFileTests = []struct {
+	name           string
+	offset, length int64
+	want           [2]int // data[want[0]:want[1]]
+	wantErr        bool
+}{
+	{"Full", 0, -1, [2]int{0, 100000}, false},
+	{"Range", 1234, 50000, [2]int{1234, 51234}, false},
+	{"Small", 10, 5, [2]int{10, 15}, false},
+	{"Rest", 99990, -1, [2]int{99990, 100000}, false},
+	{"Empty", 0, 0, [2]int{0, 0}, false},
+	{"PastEOF", 99000, 5000, [2]int{99000, 100000}, true},
+}
+
+func Test_Response_SendFile_Buffered(t *testing.T) {
+	f, data := newSendFileTestFile(t, 100000)
+
+	for _, tt := range sendFileTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			resp := GetResponse(&out)
+			defer PutResponse(resp)
+
+			resp.ContentLength = tt.length
+			resp.WriteString("HTTP/1.1 200 OK\r\n\r\n")
+			n, err := resp.SendFile(f, tt.offset, tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := resp.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			want := "HTTP/1.1 200 OK\r\n\r\n" + string(data[tt.want[0]:tt.want[1]])
+			if n != int64(tt.want[1]-tt.want[0]) {
+				t.Errorf("SendFile() = %d, want %d", n, tt.want[1]-tt.want[0])
+			}
+			if out.String() != want {
+				t.Errorf("SendFile() wrote %d bytes, want %d bytes", out.Len(), len(want))
+			}
+		})
+	}
+}
+
+func Test_Response_SendFile_TCP(t *testing.T) {
+	f, data := newSendFileTestFile(t, 100000)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	for _, tt := range sendFileTests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []byte, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					received <- nil
+					return
+				}
+				b, _ := io.ReadAll(conn)
+				conn.Close()
+				received <- b
+			}()
+
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp := GetResponse(conn)
+			resp.WriteString("HTTP/1.1 200 OK\r\n\r\n")
+			n, err := resp.SendFile(f, tt.offset, tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			resp.Flush()
+			PutResponse(resp)
+			conn.Close()
+
+			want := "HTTP/1.1 200 OK\r\n\r\n" + string(data[tt.want[0]:tt.want[1]])
+			if n != int64(tt.want[1]-tt.want[0]) {
+				t.Errorf("SendFile() = %d, want %d", n, tt.want[1]-tt.want[0])
+			}
+			if got := <-received; string(got) != want {
+				t.Errorf("SendFile() sent %d bytes, want %d bytes", len(got), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,527 @@
+package h1
+
+import (
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultReadBufferSize is the size of the per-connection read buffer when Server.ReadBufferSize is zero.
+// It limits the size of the request line and headers.
+const DefaultReadBufferSize = 8192
+
+// Server serves HTTP/1.1 connections, calling Handler for every request.
+type Server struct {
+	// Handler is called for every request. Requests get 404 Not Found if it is nil.
+	Handler Handler
+
+	// ReadBufferSize is the size of the per-connection read buffer (DefaultReadBufferSize if zero)
+	ReadBufferSize int
+
+	// DateServerHeaderFunc returns the Date and Server header lines of the responses.
+	// If nil, they are taken from a FastDateServer configured by the options below.
+	DateServerHeaderFunc func() []byte
+
+	// ServerName is the value of the Server header ("h1" if empty)
+	ServerName string
+
+	// NoServerHeader omits the Server header
+	NoServerHeader bool
+
+	// NoDateHeader omits the Date header
+	NoDateHeader bool
+
+	// ReadHeaderTimeout limits the time to read the request line and headers,
+	// from the first byte of the request (ReadTimeout if zero).
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout limits the time to read the request body, from the end of the headers.
+	ReadTimeout time.Duration
+
+	// WriteTimeout limits the time to write the response, from the end of the request headers.
+	WriteTimeout time.Duration
+
+	// IdleTimeout limits the time to wait for the next request on a keep-alive connection
+	// (ReadTimeout if zero). Timeouts are disabled if zero.
+	IdleTimeout time.Duration
+
+	// MaxConns limits the number of open connections (no limit if zero)
+	MaxConns int
+
+	// MaxConnsPerIP limits the number of open connections from a remote IP address (no limit if zero)
+	MaxConnsPerIP int
+
+	// MaxRequestsPerConn limits the number of requests served on a connection (no limit if zero).
+	// The response to the last one has a "Connection: close" header.
+	MaxRequestsPerConn int
+
+	// CloseOverLimit closes connections over MaxConns or MaxConnsPerIP immediately,
+	// instead of answering 503 Service Unavailable.
+	CloseOverLimit bool
+
+	// ReusePortListeners is the number of SO_REUSEPORT listeners opened by ListenAndServe,
+	// each served by ServeListeners from its own CPU-affine accept loop with its own pools.
+	// The kernel spreads the connections across them.
+	// Zero means a single listener, a negative value means one per GOMAXPROCS.
+	ReusePortListeners int
+
+	// Netpoll serves the connections accepted by Serve from an epoll event loop (Linux only, ignored elsewhere).
+	// A connection waiting for a request then holds no goroutine, Response or read buffer:
+	// they are taken from the pools when it becomes readable and returned when it goes idle.
+	// The read buffer comes from GetBuffer, which limits the request headers to BufferPoolSize.
+	Netpoll bool
+
+	dateServerOnce   sync.Once
+	dateServer       *FastDateServer
+	dateServerHeader func() []byte
+
+	mu         sync.Mutex
+	inShutdown int32
+	listeners  map[net.Listener]struct{}
+	conns      map[*serverConn]struct{}
+	connsPerIP map[[16]byte]int
+
+	acceptedConns uint64
+	rejectedConns uint64
+	openConns     int64
+	activeConns   int64
+}
+
+// dateServerHeaderFunc returns the DateServerHeaderFunc of the responses.
+func (s *Server) dateServerHeaderFunc() func() []byte {
+	if s.DateServerHeaderFunc != nil {
+		return s.DateServerHeaderFunc
+	}
+	s.dateServerOnce.Do(s.initDateServer)
+	return s.dateServerHeader
+}
+
+func (s *Server) initDateServer() {
+	serverName := s.ServerName
+	if len(serverName) == 0 {
+		serverName = "h1"
+	}
+	if serverName == "h1" && !s.NoServerHeader && !s.NoDateHeader {
+		// Share the per-second buffers of the default headers
+		s.dateServerHeader = DefaultFastDateServer.GetDate
+		return
+	}
+
+	if s.NoServerHeader {
+		serverName = ""
+	}
+	s.dateServer = NewFastDateServerOptions(FastDateServerOptions{
+		ServerName: serverName,
+		NoDate:     s.NoDateHeader,
+	})
+	s.dateServerHeader = s.dateServer.GetDate
+}
+
+var requestReaderPool = sync.Pool{
+	New: func() any {
+		return &RequestReader{}
+	},
+}
+
+// connPools are the pools of the objects used to serve connections.
+// Every accept loop of ServeListeners has its own, the other loops share the package pools.
+type connPools struct {
+	responses      *sync.Pool
+	bodyReaders    *sync.Pool
+	requestReaders *sync.Pool
+	buffers        *sync.Pool
+}
+
+var defaultConnPools = &connPools{
+	responses:      &ResponsePool,
+	bodyReaders:    BodyReaderPool,
+	requestReaders: &requestReaderPool,
+	buffers:        &bufferPool,
+}
+
+func newConnPools() *connPools {
+	p := &connPools{
+		responses:      &sync.Pool{},
+		bodyReaders:    &sync.Pool{},
+		requestReaders: &sync.Pool{},
+		buffers:        &sync.Pool{},
+	}
+	p.responses.New = func() any {
+		return newResponse(p.responses)
+	}
+	p.bodyReaders.New = func() any {
+		return &BodyReader{pool: p.bodyReaders}
+	}
+	p.requestReaders.New = func() any {
+		return &RequestReader{bodyReaders: p.bodyReaders, pool: p.requestReaders}
+	}
+	p.buffers.New = func() any {
+		buffer := make([]byte, BufferPoolSize)
+		return &buffer
+	}
+	return p
+}
+
+func (p *connPools) getResponse(upstream io.Writer) *Response {
+	r := p.responses.Get().(*Response)
+	r.upstream = upstream
+	return r
+}
+
+// getRequestReader returns a RequestReader reading conn with a read buffer of size bytes,
+// or without a read buffer if size is zero.
+func (p *connPools) getRequestReader(conn net.Conn, size int) *RequestReader {
+	reader := p.requestReaders.Get().(*RequestReader)
+	if cap(reader.ReadBuffer) != size {
+		reader.ReadBuffer = make([]byte, size)
+	}
+	reader.R = conn
+	reader.Reset()
+	return reader
+}
+
+func putRequestReader(reader *RequestReader) {
+	reader.R = nil
+	reader.conn = nil
+	reader.Reset()
+	if reader.pool != nil {
+		reader.pool.Put(reader)
+		return
+	}
+	requestReaderPool.Put(reader)
+}
+
+func (p *connPools) getBuffer() *[]byte {
+	return p.buffers.Get().(*[]byte)
+}
+
+func (p *connPools) putBuffer(b *[]byte) {
+	putBuffer(p.buffers, b)
+}
+
+func (s *Server) readBufferSize() int {
+	if s.ReadBufferSize <= 0 {
+		return DefaultReadBufferSize
+	}
+	return s.ReadBufferSize
+}
+
+// ListenAndServe listens on the TCP address addr and serves its connections.
+// See ReusePortListeners for serving from several listeners.
+func (s *Server) ListenAndServe(addr string) error {
+	n := s.ReusePortListeners
+	if n < 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n == 0 {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		return s.Serve(ln)
+	}
+
+	lns, err := listenReusePort(addr, n)
+	if err != nil {
+		return err
+	}
+	return s.ServeListeners(lns)
+}
+
+// Serve accepts connections on ln and serves each of them in its own goroutine
+// (or from an event loop, see Netpoll). After Shutdown, it returns ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.serve(ln, defaultConnPools)
+}
+
+// ServeListeners serves every listener from its own accept loop, with its own pools
+// of Responses, BodyReaders and buffers. On Linux, the i-th loop is pinned to the i-th CPU
+// the process may use and its listener prefers the connections arriving on that CPU (SO_INCOMING_CPU).
+// It closes the listeners and returns when a loop ends, with the first error.
+func (s *Server) ServeListeners(lns []net.Listener) error {
+	errs := make(chan error, len(lns))
+	for i, ln := range lns {
+		go func(ln net.Listener, i int) {
+			pinAcceptLoop(ln, i)
+			errs <- s.serve(ln, newConnPools())
+		}(ln, i)
+	}
+
+	err := <-errs
+	for _, ln := range lns {
+		ln.Close()
+	}
+	for i := 1; i < len(lns); i++ {
+		<-errs
+	}
+	return err
+}
+
+func (s *Server) serve(ln net.Listener, pools *connPools) error {
+	if !s.trackListener(ln, true) {
+		ln.Close()
+		return ErrServerClosed
+	}
+	defer s.trackListener(ln, false)
+
+	if s.Netpoll {
+		return s.serveNetpoll(ln, pools)
+	}
+	return s.acceptLoop(ln, func(conn net.Conn) {
+		go s.serveConn(conn, pools)
+	})
+}
+
+// acceptLoop passes the connections accepted on ln to serve.
+func (s *Server) acceptLoop(ln net.Listener, serve func(net.Conn)) error {
+	var delay time.Duration
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+
+			// Back off on temporary errors such as running out of file descriptors
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+
+		serve(conn)
+	}
+}
+
+var connectionHeader = []byte("Connection")
+var closeToken = []byte("close")
+var http10 = []byte("HTTP/1.0")
+
+// closeRequested reports whether the client does not want to send another request on the connection.
+func closeRequested(r *Request) bool {
+	if h, ok := r.GetHeader(connectionHeader); ok {
+		return stricmp(trimLWS(h.RawValue), closeToken)
+	}
+	// HTTP/1.0 keep-alive is not supported
+	return string(r.Version) == string(http10)
+}
+
+// ServeConn serves the requests of conn until the client closes it or an error occurs, then closes conn.
+// Responses to pipelined requests are flushed together.
+// During Shutdown, the connection is closed once no request is left in its read buffer.
+func (s *Server) ServeConn(conn net.Conn) error {
+	return s.serveConn(conn, defaultConnPools)
+}
+
+func (s *Server) serveConn(conn net.Conn, pools *connPools) error {
+	defer conn.Close()
+
+	sc := newServerConn(conn)
+	err := s.trackConn(sc)
+	if err != nil {
+		if err == ErrTooManyConnections && !s.CloseOverLimit {
+			s.writeOverLimit(conn)
+		}
+		return err
+	}
+	defer s.untrackConn(sc)
+
+	reader := pools.getRequestReader(conn, s.readBufferSize())
+	resp := pools.getResponse(conn)
+	defer PutResponse(resp)
+	resp.DateServerHeaderFunc = s.dateServerHeaderFunc()
+
+	_, err = s.serveRequests(sc, reader, resp, false)
+	putRequestReader(reader)
+	return err
+}
+
+// serveRequests serves the requests of sc. If yield is set, it returns with idle set
+// instead of waiting for a request once the read buffer is empty; otherwise it returns when
+// the connection must be closed.
+func (s *Server) serveRequests(sc *serverConn, reader *RequestReader, resp *Response, yield bool) (idle bool, err error) {
+	conn := sc.conn
+	reader.conn = sc
+	for first := true; ; first = false {
+		if reader.Remaining() == 0 {
+			// Until its first request, the connection stays new
+			if sc.requests > 0 {
+				s.setConnState(sc, connIdle)
+			}
+			if yield && !first {
+				return true, nil
+			}
+			s.setReadTimeout(conn, s.idleTimeout())
+			err := waitRequest(reader)
+			if err != nil {
+				if err == io.EOF || s.shuttingDown() || isTimeout(err) {
+					return false, nil
+				}
+				return false, err
+			}
+		}
+		s.setConnState(sc, connActive)
+		sc.requests++
+
+		s.setReadTimeout(conn, s.readHeaderTimeout())
+		_, err := reader.Next()
+		if err != nil {
+			if s.shuttingDown() {
+				return false, nil
+			}
+			s.setWriteTimeout(conn)
+			s.writeRequestError(resp, err)
+			return false, err
+		}
+		s.setReadTimeout(conn, s.ReadTimeout)
+		s.setWriteTimeout(conn)
+
+		// During Shutdown, the connection is closed after the last buffered request
+		closing := (s.shuttingDown() && reader.Remaining() == 0) ||
+			(s.MaxRequestsPerConn > 0 && sc.requests >= s.MaxRequestsPerConn)
+		if closing {
+			resp.AddHeader(connectionHeader, closeToken)
+		}
+
+		n, sent := resp.n, resp.sent
+		if s.Handler != nil {
+			err = s.Handler(resp, reader)
+		} else {
+			err = writeStatusText(resp, &reader.Request, 404, nil)
+		}
+		if err != nil {
+			if err != ErrHandlerTimeout && resp.sent == sent && isTimeout(err) {
+				// The body could not be read in time and nothing of the response was sent yet
+				resp.n = n
+				resp.Chunked = false
+				resp.discardBody = false
+				writeStatusText(resp, &reader.Request, 408, connectionCloseHeader)
+			}
+			resp.Flush()
+			return false, err
+		}
+
+		if closing || closeRequested(&reader.Request) {
+			return false, resp.Flush()
+		}
+
+		// The next request can only be seen once this body is skipped
+		err = reader.DiscardBody()
+		if err != nil {
+			resp.Flush()
+			return false, err
+		}
+
+		if reader.Remaining() == 0 {
+			err = resp.Flush()
+			if err != nil {
+				return false, err
+			}
+			if s.shuttingDown() {
+				return false, nil
+			}
+		}
+		resp.resetHeader()
+	}
+}
+
+// ErrTooManyConnections is returned by ServeConn for connections over MaxConns or MaxConnsPerIP.
+var ErrTooManyConnections = errors.New("too many connections")
+
+// ErrReusePortUnsupported is returned by ListenAndServe if ReusePortListeners is set on a platform without SO_REUSEPORT.
+var ErrReusePortUnsupported = errors.New("SO_REUSEPORT is not supported")
+
+var overLimitHeaders = []byte("Content-Length: 0\r\nConnection: close\r\n\r\n")
+
+// overLimitWriteTimeout bounds the time spent on the 503 response to a rejected connection.
+const overLimitWriteTimeout = time.Second
+
+// writeOverLimit answers 503 Service Unavailable without reading the request.
+func (s *Server) writeOverLimit(conn net.Conn) {
+	buffer := GetBuffer()
+	defer PutBuffer(buffer)
+
+	*buffer = append((*buffer)[:0], GetStatusLine(503)...)
+	*buffer = append(*buffer, s.dateServerHeaderFunc()()...)
+	*buffer = append(*buffer, overLimitHeaders...)
+
+	conn.SetWriteDeadline(time.Now().Add(overLimitWriteTimeout))
+	conn.Write(*buffer)
+}
+
+// waitRequest waits for the first bytes of the next request.
+func waitRequest(reader *RequestReader) error {
+	for reader.Remaining() == 0 {
+		_, err := reader.Fill()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) hasReadTimeouts() bool {
+	return s.ReadHeaderTimeout > 0 || s.ReadTimeout > 0 || s.IdleTimeout > 0
+}
+
+func (s *Server) readHeaderTimeout() time.Duration {
+	if s.ReadHeaderTimeout > 0 {
+		return s.ReadHeaderTimeout
+	}
+	return s.ReadTimeout
+}
+
+func (s *Server) idleTimeout() time.Duration {
+	if s.IdleTimeout > 0 {
+		return s.IdleTimeout
+	}
+	return s.ReadTimeout
+}
+
+// setReadTimeout sets the read deadline of conn to d from now, or clears it if d is zero.
+func (s *Server) setReadTimeout(conn net.Conn, d time.Duration) {
+	if !s.hasReadTimeouts() {
+		return
+	}
+	var deadline time.Time
+	if d > 0 {
+		deadline = time.Now().Add(d)
+	}
+	conn.SetReadDeadline(deadline)
+}
+
+func (s *Server) setWriteTimeout(conn net.Conn) {
+	if s.WriteTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+	}
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// writeRequestError answers a request that could not be read.
+// Nothing is written for connection errors other than timeouts.
+func (s *Server) writeRequestError(resp *Response, err error) {
+	var ne net.Error
+	if err == io.ErrUnexpectedEOF || errors.Is(err, net.ErrClosed) || (errors.As(err, &ne) && !ne.Timeout()) {
+		return
+	}
+
+	resp.resetHeader()
+	writeStatusText(resp, &Request{}, ErrorStatus(err), nil)
+	resp.Flush()
+}
@@ -0,0 +1,412 @@
+package h1
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testDate = time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)
+
+// serveTestRequest runs h on the request and returns the response it wrote, without the Date and Server headers.
+func serveTestRequest(t *testing.T, h Handler, request string) string {
+	t.Helper()
+	var out strings.Builder
+	reader := newTestRequestReader(request)
+	if _, err := reader.Next(); err != nil {
+		t.Fatal(err)
+	}
+	resp := GetResponse(&out)
+	defer PutResponse(resp)
+	resp.OmitDateServerHeader = true
+	if err := h(resp, reader); err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+// serveTestConn serves the requests written by the client over a pipe and returns everything the server wrote.
+func serveTestConn(s *Server, requests string) (string, error) {
+	client, server := net.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ServeConn(server)
+	}()
+	go func() {
+		client.Write([]byte(requests))
+	}()
+
+	out, _ := io.ReadAll(client)
+	client.Close()
+	return string(out), <-done
+}
+
+// serveTestConnSlowly is serveTestConn with the requests written size bytes at a time, pausing for delay after every write.
+func serveTestConnSlowly(s *Server, requests string, size int, delay time.Duration) (string, error) {
+	client, server := net.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ServeConn(server)
+	}()
+	go func() {
+		for i := 0; i < len(requests); i += size {
+			end := i + size
+			if end > len(requests) {
+				end = len(requests)
+			}
+			if _, err := client.Write([]byte(requests[i:end])); err != nil {
+				return
+			}
+			time.Sleep(delay)
+		}
+	}()
+
+	out, _ := io.ReadAll(client)
+	client.Close()
+	return string(out), <-done
+}
+
+func helloHandler(resp *Response, req *RequestReader) error {
+	resp.ContentLength = int64(len(req.Request.URI.Path()))
+	resp.WriteHeader(200)
+	resp.EndHeader()
+	_, err := resp.Write(req.Request.URI.Path())
+	return err
+}
+
+func Test_Server_ServeConn(t *testing.T) {
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	s := &Server{
+		Handler:              helloHandler,
+		DateServerHeaderFunc: fds.GetDate,
+	}
+
+	tests := []struct {
+		name     string
+		requests string
+		want     string
+	}{
+		{
+			"Single",
+			"GET /hello HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 6\r\n\r\n/hello",
+		},
+		{
+			"Pipelined",
+			"GET /a HTTP/1.1\r\n\r\nGET /b HTTP/1.1\r\nConnection: close\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a" +
+				"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/b",
+		},
+		{
+			"Unread Body",
+			"POST /a HTTP/1.1\r\nContent-Length: 19\r\n\r\nGET /x HTTP/1.1\r\n\r\nGET /b HTTP/1.0\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a" +
+				"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/b",
+		},
+		{
+			"Bad Request",
+			"GET /a HTTP/1.1\r\nConnection: keep-alive\r\n\r\nGET /%zz HTTP/1.1\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a" +
+				"HTTP/1.1 400 Bad Request\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 15\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n400 Bad Request",
+		},
+		{
+			"Negative Content-Length",
+			"POST /a HTTP/1.1\r\nContent-Length: -1\r\n\r\n",
+			"HTTP/1.1 400 Bad Request\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 15\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n400 Bad Request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := serveTestConn(s, tt.requests)
+			if out != tt.want {
+				t.Errorf("ServeConn() wrote\n%q\nwant\n%q", out, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Server_NilHandler(t *testing.T) {
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	s := &Server{DateServerHeaderFunc: fds.GetDate}
+
+	got, err := serveTestConn(s, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "HTTP/1.1 404 Not Found\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 13\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n404 Not Found"
+	if got != want {
+		t.Errorf("ServeConn() wrote %q, want %q", got, want)
+	}
+}
+
+func Test_Server_Serve(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := &Server{Handler: helloHandler}
+	go s.Serve(ln)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /tcp HTTP/1.1\r\nConnection: close\r\n\r\n"))
+	out, _ := io.ReadAll(conn)
+	if len(out) < 4 || string(out[len(out)-4:]) != "/tcp" {
+		t.Errorf("response = %q", out)
+	}
+}
+
+func Test_Server_DateServerOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		server *Server
+		want   string
+	}{
+		{"Default", &Server{}, "Date: * GMT\r\nServer: h1\r\n"},
+		{"Server Name", &Server{ServerName: "edge"}, "Date: * GMT\r\nServer: edge\r\n"},
+		{"No Server", &Server{ServerName: "edge", NoServerHeader: true}, "Date: * GMT\r\n"},
+		{"No Date", &Server{NoDateHeader: true}, "Server: h1\r\n"},
+		{"None", &Server{NoServerHeader: true, NoDateHeader: true}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Handler = helloHandler
+			out, err := serveTestConn(tt.server, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers := strings.TrimPrefix(out, "HTTP/1.1 200 OK\r\n")
+			headers = strings.TrimSuffix(headers, "Content-Length: 1\r\n\r\n/")
+
+			// The date itself is not deterministic
+			if i := strings.Index(headers, "Date: "); i >= 0 {
+				headers = headers[:i+len("Date: ")] + "*" + headers[i+len("Date: ")+HTTPDateLen-len(" GMT"):]
+			}
+			if headers != tt.want {
+				t.Errorf("headers = %q, want %q", headers, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Response_OmitDateServerHeader(t *testing.T) {
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	s := &Server{
+		DateServerHeaderFunc: fds.GetDate,
+		Handler: func(resp *Response, req *RequestReader) error {
+			resp.OmitDateServerHeader = string(req.Request.URI.Path()) == "/bare"
+			return helloHandler(resp, req)
+		},
+	}
+
+	got, err := serveTestConn(s, "GET /bare HTTP/1.1\r\n\r\nGET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n/bare" +
+		"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 1\r\n\r\n/"
+	if got != want {
+		t.Errorf("ServeConn() wrote %q, want %q", got, want)
+	}
+}
+
+func Test_Server_FragmentedRequests(t *testing.T) {
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	s := &Server{Handler: helloHandler, DateServerHeaderFunc: fds.GetDate}
+
+	requests := "GET /a HTTP/1.1\r\nHost: example.com\r\nUser-Agent: test\r\nAccept: */*\r\n\r\n" +
+		"GET /b HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n"
+	want := "HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a" +
+		"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/b"
+
+	for _, size := range []int{1, 3, 7} {
+		out, err := serveTestConnSlowly(s, requests, size, 0)
+		if err != nil {
+			t.Errorf("ServeConn() with %d byte writes error = %v", size, err)
+		}
+		if out != want {
+			t.Errorf("ServeConn() with %d byte writes wrote\n%q\nwant\n%q", size, out, want)
+		}
+	}
+}
+
+func Test_Server_Timeouts(t *testing.T) {
+	bodyHandler := func(resp *Response, req *RequestReader) error {
+		body := req.Body()
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		resp.ContentLength = int64(len(data))
+		resp.WriteHeader(200)
+		resp.EndHeader()
+		_, err = resp.Write(data)
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		server   *Server
+		requests string
+		want     string
+	}{
+		{
+			"Header",
+			&Server{Handler: helloHandler, ReadHeaderTimeout: 20 * time.Millisecond},
+			"GET /a HTTP/1.1\r\nHost: exam",
+			"HTTP/1.1 408 Request Timeout\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 19\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n408 Request Timeout",
+		},
+		{
+			"Idle",
+			&Server{Handler: helloHandler, IdleTimeout: 20 * time.Millisecond, ReadTimeout: time.Minute},
+			"GET /a HTTP/1.1\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a",
+		},
+		{
+			"Body",
+			&Server{Handler: bodyHandler, ReadTimeout: 20 * time.Millisecond},
+			"POST /a HTTP/1.1\r\nContent-Length: 10\r\n\r\nab",
+			"HTTP/1.1 408 Request Timeout\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 19\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\n\r\n408 Request Timeout",
+		},
+		{
+			"Body In Time",
+			&Server{Handler: bodyHandler, ReadTimeout: time.Minute, IdleTimeout: 20 * time.Millisecond},
+			"POST /a HTTP/1.1\r\nContent-Length: 2\r\n\r\nab",
+			"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\nab",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fds := NewTestFastDateServer("h1", FixedClock(testDate))
+			tt.server.DateServerHeaderFunc = fds.GetDate
+			out, _ := serveTestConn(tt.server, tt.requests)
+			if out != tt.want {
+				t.Errorf("ServeConn() wrote\n%q\nwant\n%q", out, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Server_SlowHeader(t *testing.T) {
+	requests := "GET /a HTTP/1.1\r\nHost: example.com\r\nUser-Agent: slow\r\nConnection: close\r\n\r\n"
+
+	tests := []struct {
+		name  string
+		delay time.Duration
+		want  string
+	}{
+		{"In Time", 5 * time.Millisecond, "HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a"},
+		{"Too Slow", 50 * time.Millisecond, "HTTP/1.1 408 Request Timeout\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 19\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n408 Request Timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fds := NewTestFastDateServer("h1", FixedClock(testDate))
+			s := &Server{Handler: helloHandler, DateServerHeaderFunc: fds.GetDate, ReadHeaderTimeout: 200 * time.Millisecond}
+
+			// The head is sent in 10 writes
+			out, _ := serveTestConnSlowly(s, requests, 8, tt.delay)
+			if out != tt.want {
+				t.Errorf("ServeConn() wrote\n%q\nwant\n%q", out, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Server_WriteTimeout(t *testing.T) {
+	s := &Server{Handler: helloHandler, WriteTimeout: 20 * time.Millisecond}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go client.Write([]byte("GET /a HTTP/1.1\r\n\r\n"))
+
+	// The client never reads the response
+	err := s.ServeConn(server)
+	if !isTimeout(err) {
+		t.Errorf("ServeConn() error = %v, want a timeout", err)
+	}
+}
+
+func Test_Server_MaxRequestsPerConn(t *testing.T) {
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	s := &Server{Handler: helloHandler, DateServerHeaderFunc: fds.GetDate, MaxRequestsPerConn: 2}
+
+	out, err := serveTestConn(s, "GET /a HTTP/1.1\r\n\r\nGET /b HTTP/1.1\r\n\r\nGET /c HTTP/1.1\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a" +
+		"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nConnection: close\r\nContent-Length: 2\r\n\r\n/b"
+	if out != want {
+		t.Errorf("ServeConn() wrote\n%q\nwant\n%q", out, want)
+	}
+}
+
+func Test_Server_ConnLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		server *Server
+		want   string
+	}{
+		{"MaxConns", &Server{MaxConns: 1}, "HTTP/1.1 503 Service Unavailable\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"},
+		{"MaxConnsPerIP", &Server{MaxConns: 10, MaxConnsPerIP: 1}, "HTTP/1.1 503 Service Unavailable\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"},
+		{"CloseOverLimit", &Server{MaxConns: 1, CloseOverLimit: true}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Skip(err)
+			}
+			defer ln.Close()
+			fds := NewTestFastDateServer("h1", FixedClock(testDate))
+			s := tt.server
+			s.Handler = helloHandler
+			s.DateServerHeaderFunc = fds.GetDate
+			go s.Serve(ln)
+
+			first, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer first.Close()
+			waitFor(t, "first connection", func() bool { return s.Stats().Open == 1 })
+
+			second, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer second.Close()
+			out, _ := io.ReadAll(second)
+			if string(out) != tt.want {
+				t.Errorf("over limit connection read %q, want %q", out, tt.want)
+			}
+			if stats := s.Stats(); stats.Rejected != 1 || stats.Open != 1 {
+				t.Errorf("Stats() = %+v", stats)
+			}
+
+			// The first connection is still served
+			first.Write([]byte("GET /first HTTP/1.1\r\nConnection: close\r\n\r\n"))
+			out, _ = io.ReadAll(first)
+			if !strings.HasSuffix(string(out), "/first") {
+				t.Errorf("first connection read %q", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package h1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ErrServerClosed is returned by Serve and ServeConn after Shutdown.
+var ErrServerClosed = errors.New("server closed")
+
+// ConnStats counts the connections of a Server.
+type ConnStats struct {
+	Accepted uint64 // connections served since the start
+	Rejected uint64 // connections over MaxConns or MaxConnsPerIP since the start
+	Open     int64  // currently open connections
+	Active   int64  // open connections serving a request
+	Idle     int64  // open connections waiting for a request
+}
+
+const (
+	connNew uint32 = iota
+	connIdle
+	connActive
+)
+
+// newConnGracePeriod is the time Shutdown leaves a new connection to send its first request.
+const newConnGracePeriod = 5 * time.Second
+
+// serverConn is a connection tracked for Shutdown and the connection limits.
+type serverConn struct {
+	conn     net.Conn
+	state    uint32
+	ip       [16]byte
+	hasIP    bool
+	requests int
+	created  int64 // UnixNano
+
+	// close replaces conn.Close for connections owned by an event loop
+	close func()
+}
+
+// Stats returns the current connection counters.
+func (s *Server) Stats() ConnStats {
+	open := atomic.LoadInt64(&s.openConns)
+	active := atomic.LoadInt64(&s.activeConns)
+	return ConnStats{
+		Accepted: atomic.LoadUint64(&s.acceptedConns),
+		Rejected: atomic.LoadUint64(&s.rejectedConns),
+		Open:     open,
+		Active:   active,
+		Idle:     open - active,
+	}
+}
+
+func (s *Server) shuttingDown() bool {
+	return atomic.LoadInt32(&s.inShutdown) != 0
+}
+
+// trackListener adds or removes ln from the listeners closed by Shutdown.
+func (s *Server) trackListener(ln net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !add {
+		delete(s.listeners, ln)
+		return true
+	}
+	if s.shuttingDown() {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[ln] = struct{}{}
+	return true
+}
+
+func newServerConn(conn net.Conn) *serverConn {
+	sc := &serverConn{conn: conn, state: connNew, created: time.Now().UnixNano()}
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		copy(sc.ip[:], addr.IP.To16())
+		sc.hasIP = true
+	}
+	return sc
+}
+
+// trackConn registers sc as new. It returns ErrServerClosed after Shutdown
+// and ErrTooManyConnections if sc is over a connection limit.
+// Shutdown may call sc.close as soon as sc is registered, so it must be set before.
+func (s *Server) trackConn(sc *serverConn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shuttingDown() {
+		return ErrServerClosed
+	}
+	if (s.MaxConns > 0 && len(s.conns) >= s.MaxConns) ||
+		(s.MaxConnsPerIP > 0 && sc.hasIP && s.connsPerIP[sc.ip] >= s.MaxConnsPerIP) {
+		atomic.AddUint64(&s.rejectedConns, 1)
+		return ErrTooManyConnections
+	}
+
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]struct{})
+	}
+	s.conns[sc] = struct{}{}
+	if sc.hasIP && s.MaxConnsPerIP > 0 {
+		if s.connsPerIP == nil {
+			s.connsPerIP = make(map[[16]byte]int)
+		}
+		s.connsPerIP[sc.ip]++
+	}
+	atomic.AddUint64(&s.acceptedConns, 1)
+	atomic.AddInt64(&s.openConns, 1)
+	return nil
+}
+
+func (s *Server) untrackConn(sc *serverConn) {
+	s.setConnState(sc, connIdle)
+
+	s.mu.Lock()
+	delete(s.conns, sc)
+	if n, ok := s.connsPerIP[sc.ip]; ok && sc.hasIP {
+		if n <= 1 {
+			delete(s.connsPerIP, sc.ip)
+		} else {
+			s.connsPerIP[sc.ip] = n - 1
+		}
+	}
+	s.mu.Unlock()
+	atomic.AddInt64(&s.openConns, -1)
+}
+
+func (s *Server) setConnState(sc *serverConn, state uint32) {
+	old := atomic.SwapUint32(&sc.state, state)
+	if old == state {
+		return
+	}
+	if state == connActive {
+		atomic.AddInt64(&s.activeConns, 1)
+	} else if old == connActive {
+		atomic.AddInt64(&s.activeConns, -1)
+	}
+}
+
+// closeIdleConns closes the connections waiting for a request and reports whether none are left open.
+// A new connection is only closed once it had newConnGracePeriod to send its first request.
+func (s *Server) closeIdleConns() bool {
+	expired := time.Now().Add(-newConnGracePeriod).UnixNano()
+
+	s.mu.Lock()
+	var idle []*serverConn
+	for sc := range s.conns {
+		state := atomic.LoadUint32(&sc.state)
+		if state == connIdle || (state == connNew && sc.created < expired) {
+			idle = append(idle, sc)
+		}
+	}
+	s.mu.Unlock()
+
+	// An event loop connection is untracked by its close function
+	for _, sc := range idle {
+		if sc.close != nil {
+			sc.close()
+		} else {
+			sc.conn.Close()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns) == 0
+}
+
+func (s *Server) closeAllConns() {
+	s.mu.Lock()
+	conns := make([]*serverConn, 0, len(s.conns))
+	for sc := range s.conns {
+		conns = append(conns, sc)
+	}
+	s.mu.Unlock()
+
+	for _, sc := range conns {
+		if sc.close != nil {
+			sc.close()
+		} else {
+			sc.conn.Close()
+		}
+	}
+}
+
+// Shutdown stops the server gracefully. It closes the listeners, lets every connection finish
+// its current request (and the pipelined requests already read), closes idle connections
+// and waits until all connections are closed. A new connection has 5 seconds to send its first request.
+//
+// If ctx is done first, the remaining connections are closed and ctx.Err() is returned.
+// The Server cannot be reused after Shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	atomic.StoreInt32(&s.inShutdown, 1)
+	var err error
+	for ln := range s.listeners {
+		if cerr := ln.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	s.mu.Unlock()
+
+	defer s.stopDateServer()
+
+	delay := time.Millisecond
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for !s.closeIdleConns() {
+		select {
+		case <-ctx.Done():
+			s.closeAllConns()
+			return ctx.Err()
+		case <-timer.C:
+			if delay *= 2; delay > 100*time.Millisecond {
+				delay = 100 * time.Millisecond
+			}
+			timer.Reset(delay)
+		}
+	}
+	return err
+}
+
+// stopDateServer stops the goroutine of the date server. Late responses get its last date.
+func (s *Server) stopDateServer() {
+	// dateServerHeaderFunc must not return nil afterwards
+	s.dateServerOnce.Do(s.initDateServer)
+	if s.dateServer != nil {
+		s.dateServer.Stop()
+	}
+}
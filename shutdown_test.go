@@ -0,0 +1,247 @@
+package h1
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it is true or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func Test_Server_Shutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	release := make(chan struct{})
+	s := &Server{
+		Handler: func(resp *Response, req *RequestReader) error {
+			<-release
+			return helloHandler(resp, req)
+		},
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ln)
+	}()
+
+	busy, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	idle, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+
+	// The idle connection has served a request and waits for the next one
+	idle.Write([]byte("GET /idle HTTP/1.1\r\n\r\n"))
+	release <- struct{}{}
+	if _, err := http.ReadResponse(bufio.NewReader(idle), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	busy.Write([]byte("GET /busy HTTP/1.1\r\n\r\n"))
+	waitFor(t, "connections", func() bool {
+		stats := s.Stats()
+		return stats.Open == 2 && stats.Active == 1 && stats.Idle == 1
+	})
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Serve() error = %v, want %v", err, ErrServerClosed)
+	}
+	if n, err := idle.Read(make([]byte, 1)); n != 0 || err == nil {
+		t.Errorf("idle connection read %d bytes, err = %v", n, err)
+	}
+
+	// The request started before Shutdown, the connection is closed after its response
+	close(release)
+	out, _ := io.ReadAll(busy)
+	if !strings.HasSuffix(string(out), "/busy") {
+		t.Errorf("response = %q", out)
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if stats := s.Stats(); stats != (ConnStats{Accepted: 2}) {
+		t.Errorf("Stats() = %+v after Shutdown", stats)
+	}
+	if err := s.Serve(ln); err != ErrServerClosed {
+		t.Errorf("Serve() after Shutdown error = %v, want %v", err, ErrServerClosed)
+	}
+}
+
+func Test_Server_Shutdown_Pipelined(t *testing.T) {
+	fds := NewTestFastDateServer("h1", FixedClock(testDate))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := &Server{
+		DateServerHeaderFunc: fds.GetDate,
+		Handler: func(resp *Response, req *RequestReader) error {
+			if string(req.Request.URI.Path()) == "/a" {
+				close(started)
+				<-release
+			}
+			return helloHandler(resp, req)
+		},
+	}
+
+	shutdown := make(chan error, 1)
+	go func() {
+		<-started
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	go func() {
+		waitFor(t, "shutdown", s.shuttingDown)
+		close(release)
+	}()
+
+	out, err := serveTestConn(s, "GET /a HTTP/1.1\r\n\r\nGET /b HTTP/1.1\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nContent-Length: 2\r\n\r\n/a" +
+		"HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: h1\r\nConnection: close\r\nContent-Length: 2\r\n\r\n/b"
+	if out != want {
+		t.Errorf("ServeConn() wrote\n%q\nwant\n%q", out, want)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+func Test_Server_Shutdown_Deadline(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s := &Server{
+		Handler: func(resp *Response, req *RequestReader) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go s.ServeConn(server)
+	go client.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := server.Write([]byte("x")); err == nil {
+		t.Error("connection is still open")
+	}
+}
+
+func Test_Server_Shutdown_NewConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := &Server{Handler: helloHandler}
+	go s.Serve(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitFor(t, "new connection", func() bool { return s.Stats().Open == 1 })
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	waitFor(t, "shutdown", s.shuttingDown)
+	time.Sleep(10 * time.Millisecond)
+
+	// The first request of a new connection is served within the grace period
+	conn.Write([]byte("GET /a HTTP/1.1\r\n\r\n"))
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 || !res.Close {
+		t.Errorf("response = %d, close %v, want 200 and Connection: close", res.StatusCode, res.Close)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+func Test_Server_Shutdown_DateServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"Drained", time.Second},
+		{"Deadline", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{ServerName: "test"}
+			getDate := s.dateServerHeaderFunc()
+			getDate() // starts the goroutine
+			if tt.timeout == 0 {
+				// A connection that never goes idle
+				client, server := net.Pipe()
+				defer client.Close()
+				sc := newServerConn(server)
+				s.trackConn(sc)
+				s.setConnState(sc, connActive)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			s.Shutdown(ctx)
+			if state := atomic.LoadUint32(&s.dateServer.state); state != dateServerStopped {
+				t.Errorf("date server state = %d, want stopped", state)
+			}
+			if date := string(getDate()); !strings.HasSuffix(date, "Server: test\r\n") {
+				t.Errorf("date header = %q", date)
+			}
+		})
+	}
+
+	// A server shut down before any response still writes the 503 of writeOverLimit
+	s := &Server{}
+	s.Shutdown(context.Background())
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		s.writeOverLimit(server)
+		server.Close()
+	}()
+	out, _ := io.ReadAll(client)
+	if !strings.HasPrefix(string(out), "HTTP/1.1 503 Service Unavailable\r\nDate: ") {
+		t.Errorf("writeOverLimit() wrote %q", out)
+	}
+}
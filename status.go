@@ -100,3 +100,21 @@ func GetStatusLine(status int) []byte {
 	status = status & statusMask
 	return statusLine[status]
 }
+
+// ErrorStatus returns the response status for an error returned while reading a request.
+// Timeouts map to 408 Request Timeout, unknown errors to 400 Bad Request.
+func ErrorStatus(err error) int {
+	if isTimeout(err) {
+		return http.StatusRequestTimeout
+	}
+	switch err {
+	case ErrRequestHeaderTooLarge:
+		return http.StatusRequestHeaderFieldsTooLarge
+	case ErrFormTooLarge, ErrDecompressedBodyTooLarge, ErrMultipartTooLarge, ErrBodyTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrUnsupportedContentEncoding:
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -10,7 +10,21 @@ func stricmp(a, b []byte) bool {
 	}
 
 	// Slow Path
-	for i := 0; i < len(a); i++ {
+	return stricmpFold(a, b)
+}
+
+// stricmpFold compares a and b (of equal length) case-insensitively, 8 bytes at a time.
+// It is kept out of line so that stricmp itself stays inlinable.
+//
+//go:noinline
+func stricmpFold(a, b []byte) bool {
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		if !foldEqual8(swarLoad(a[i:]), swarLoad(b[i:])) {
+			return false
+		}
+	}
+	for ; i < len(a); i++ {
 		if !( /* case-insensitive */ (a[i] | 0x20) == (b[i] | 0x20)) {
 			return false
 		}
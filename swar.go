@@ -0,0 +1,152 @@
+package h1
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// SWAR (SIMD Within A Register) helpers.
+// These scan 8 bytes at a time in pure Go and fall back to a byte loop for the tail.
+// indexNonToken, skipLWS, skipLWSReverse and stricmp always use them. Searches for a single byte
+// only do in the purego build: indexByte calls bytes.IndexByte otherwise, see scan.go.
+// Every mask below has the high bit of byte i set if byte i matches.
+// Some masks may have false positives above the first match, so only the lowest set byte is used.
+
+const (
+	swarLSB = 0x0101010101010101
+	swarMSB = 0x8080808080808080
+	swarLow = 0x7f7f7f7f7f7f7f7f
+)
+
+func swarLoad(b []byte) uint64 {
+	return binary.LittleEndian.Uint64(b)
+}
+
+// swarEqual returns an exact mask of the bytes in x equal to c.
+func swarEqual(x uint64, c byte) uint64 {
+	x ^= swarLSB * uint64(c)
+	return ^(((x & swarLow) + swarLow) | x) & swarMSB
+}
+
+// swarLess returns a mask of the bytes in x less than n (n <= 128).
+func swarLess(x uint64, n byte) uint64 {
+	return (x - swarLSB*uint64(n)) &^ x & swarMSB
+}
+
+// swarBetween returns an exact mask of the bytes in x strictly between m and n (m, n <= 127).
+func swarBetween(x uint64, m, n byte) uint64 {
+	return (swarLSB*(127+uint64(n)) - (x & swarLow)) &^ x & ((x & swarLow) + swarLSB*(127-uint64(m))) & swarMSB
+}
+
+// swarHighOrDEL returns an exact mask of the bytes in x that are 0x7f or have the high bit set.
+func swarHighOrDEL(x uint64) uint64 {
+	return (((x & swarLow) + swarLSB) | x) & swarMSB
+}
+
+func swarFirst(mask uint64) int {
+	return bits.TrailingZeros64(mask) >> 3
+}
+
+func swarLast(mask uint64) int {
+	return (63 - bits.LeadingZeros64(mask)) >> 3
+}
+
+// swarIndexByte returns the index of the first c in b, or -1. It backs indexByte in the purego build only.
+func swarIndexByte(b []byte, c byte) int {
+	i := 0
+	for ; i+8 <= len(b); i += 8 {
+		if mask := swarEqual(swarLoad(b[i:]), c); mask != 0 {
+			return i + swarFirst(mask)
+		}
+	}
+	for ; i < len(b); i++ {
+		if b[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// tokenTable reports whether a byte is a tchar (RFC 9110 Section 5.6.2).
+var tokenTable [256]bool
+
+var _ = func() int {
+	const tchar = "!#$%&'*+-.^_`|~0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	for _, c := range []byte(tchar) {
+		tokenTable[c] = true
+	}
+	return 0
+}()
+
+// swarNonToken returns a mask of the bytes in x that are not tchar.
+func swarNonToken(x uint64) uint64 {
+	return swarLess(x, 0x21) | // CTL and SP
+		swarHighOrDEL(x) |
+		swarBetween(x, 0x21, 0x23) | // "
+		swarBetween(x, 0x27, 0x2a) | // ( )
+		swarBetween(x, 0x2b, 0x2d) | // ,
+		swarBetween(x, 0x2e, 0x30) | // /
+		swarBetween(x, 0x39, 0x41) | // : ; < = > ? @
+		swarBetween(x, 0x5a, 0x5e) | // [ \ ]
+		swarBetween(x, 0x7a, 0x7c) | // {
+		swarBetween(x, 0x7c, 0x7e) // }
+}
+
+// indexNonToken returns the index of the first byte in b that is not a tchar, or len(b).
+// For a header line this finds the colon and validates the field name in one pass.
+func indexNonToken(b []byte) int {
+	i := 0
+	for ; i+8 <= len(b); i += 8 {
+		if mask := swarNonToken(swarLoad(b[i:])); mask != 0 {
+			return i + swarFirst(mask)
+		}
+	}
+	for ; i < len(b); i++ {
+		if !tokenTable[b[i]] {
+			return i
+		}
+	}
+	return len(b)
+}
+
+// swarNonLWS returns an exact mask of the bytes in x that are neither SP nor HTAB.
+func swarNonLWS(x uint64) uint64 {
+	return ^(swarEqual(x, ' ') | swarEqual(x, '\t')) & swarMSB
+}
+
+// skipLWS returns the index of the first byte in b[i:j] that is not SP or HTAB, or j.
+func skipLWS(b []byte, i, j int) int {
+	for ; i+8 <= j; i += 8 {
+		if mask := swarNonLWS(swarLoad(b[i:])); mask != 0 {
+			return i + swarFirst(mask)
+		}
+	}
+	for ; i < j; i++ {
+		if b[i] != ' ' && b[i] != '\t' {
+			return i
+		}
+	}
+	return j
+}
+
+// skipLWSReverse moves j backwards to the last byte in b[i:j+1] that is not SP or HTAB, stopping at i.
+// If j <= i, j is returned as is.
+func skipLWSReverse(b []byte, i, j int) int {
+	for ; j-7 > i; j -= 8 {
+		if mask := swarNonLWS(swarLoad(b[j-7:])); mask != 0 {
+			return j - 7 + swarLast(mask)
+		}
+	}
+	for ; j > i; j-- {
+		if b[j] != ' ' && b[j] != '\t' {
+			break
+		}
+	}
+	return j
+}
+
+// foldEqual8 reports whether a and b are equal after setting the 0x20 bit of every byte.
+func foldEqual8(a, b uint64) bool {
+	const fold = swarLSB * 0x20
+	return a|fold == b|fold
+}
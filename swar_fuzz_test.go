@@ -0,0 +1,94 @@
+//go:build go1.18
+
+package h1
+
+import (
+	"bytes"
+	"testing"
+)
+
+// The fuzz targets extend Test_SWAR_Differential beyond its mutations of swarSeeds.
+
+func addSWARSeeds(f *testing.F) {
+	for _, seed := range swarSeeds {
+		f.Add(seed)
+	}
+}
+
+func FuzzIndexNonToken(f *testing.F) {
+	addSWARSeeds(f)
+	f.Add([]byte("Content-Type:"))
+	f.Add([]byte("!#$%&'*+-.^_`|~09AZaz\x7f"))
+	f.Add([]byte("abcdefgh\x80"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if got, want := indexNonToken(data), indexNonTokenReference(data); got != want {
+			t.Fatalf("indexNonToken(%q) = %d, want %d", data, got, want)
+		}
+	})
+}
+
+func FuzzIndexByte(f *testing.F) {
+	for _, seed := range swarSeeds {
+		for _, c := range []byte{'\n', ':', ' ', '&', '='} {
+			f.Add(seed, c)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte, c byte) {
+		if got, want := swarIndexByte(data, c), bytes.IndexByte(data, c); got != want {
+			t.Fatalf("swarIndexByte(%q, %q) = %d, want %d", data, c, got, want)
+		}
+	})
+}
+
+func FuzzSkipLWS(f *testing.F) {
+	addSWARSeeds(f)
+	f.Add([]byte(" \t \t \t \t \t x \t"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		for i := 0; i <= len(data); i++ {
+			for _, j := range []int{i, len(data)} {
+				if got, want := skipLWS(data, i, j), skipLWSReference(data, i, j); got != want {
+					t.Fatalf("skipLWS(%q, %d, %d) = %d, want %d", data, i, j, got, want)
+				}
+				if got, want := skipLWSReverse(data, i, j-1), skipLWSReverseReference(data, i, j-1); got != want {
+					t.Fatalf("skipLWSReverse(%q, %d, %d) = %d, want %d", data, i, j-1, got, want)
+				}
+			}
+		}
+	})
+}
+
+func FuzzParseHeaderLine(f *testing.F) {
+	addSWARSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		line, rest, err := splitLine(data)
+		wantLine, wantRest, wantErr := splitLineReference(data)
+		if !bytes.Equal(line, wantLine) || !bytes.Equal(rest, wantRest) || err != wantErr {
+			t.Fatalf("splitLine(%q) mismatch", data)
+		}
+
+		next := data
+		for {
+			line, next, err = splitLine(next)
+			if err != nil {
+				break
+			}
+			checkParseHeaderLine(t, line)
+		}
+	})
+}
+
+func FuzzStricmp(f *testing.F) {
+	for _, seed := range swarSeeds {
+		f.Add(seed, bytes.ToUpper(seed))
+		f.Add(seed, bytes.ToLower(seed))
+	}
+	f.Add([]byte("content-length"), []byte("Content-Lengt\xc8"))
+	f.Fuzz(func(t *testing.T, a, b []byte) {
+		if got, want := stricmp(a, b), stricmpReference(a, b); got != want {
+			t.Fatalf("stricmp(%q, %q) = %v, want %v", a, b, got, want)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package h1
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// Reference implementations (the byte-at-a-time parser before SWAR scanning).
+
+func splitLineReference(src []byte) (line, rest []byte, err error) {
+	idx := bytes.IndexByte(src, '\n')
+	if idx < 1 {
+		return nil, src, ErrBufferTooSmall
+	}
+	if src[idx-1] == '\r' {
+		return src[:idx-1], src[idx+1:], nil
+	}
+	return src[:idx], src[idx+1:], nil
+}
+
+func parseHeaderLineReference(src []byte) (name []byte, value []byte) {
+	idx := bytes.IndexByte(src, ':')
+	if idx < 0 {
+		return src[:0], nil
+	}
+	var i int = idx + 1
+	for ; i < len(src); i++ {
+		if src[i] != ' ' && src[i] != '\t' {
+			break
+		}
+	}
+	var j int = len(src) - 1
+	for ; j > i; j-- {
+		if src[j] != ' ' && src[j] != '\t' {
+			break
+		}
+	}
+	return src[:idx], src[i : j+1]
+}
+
+func skipLWSReference(b []byte, i, j int) int {
+	for ; i < j; i++ {
+		if b[i] != ' ' && b[i] != '\t' {
+			break
+		}
+	}
+	return i
+}
+
+func skipLWSReverseReference(b []byte, i, j int) int {
+	for ; j > i; j-- {
+		if b[j] != ' ' && b[j] != '\t' {
+			break
+		}
+	}
+	return j
+}
+
+func stricmpReference(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if (a[i] | 0x20) != (b[i] | 0x20) {
+			return false
+		}
+	}
+	return true
+}
+
+func indexNonTokenReference(b []byte) int {
+	for i := range b {
+		if !tokenTable[b[i]] {
+			return i
+		}
+	}
+	return len(b)
+}
+
+// swarSeeds are mutated by Test_SWAR_Differential and seed the fuzz targets.
+var swarSeeds = [][]byte{
+	TestFullReqData,
+	[]byte("POST /upload?a=1&b=&debug HTTP/1.1\r\nHost: localhost\r\nContent-Length: 12\r\n\r\nHello World!"),
+	[]byte("GET / HTTP/1.1\nX-Spaces:    \t  value with spaces \t   \nX-Empty:\n\n"),
+	[]byte("Bad Header Name: value\r\nx(y): z\r\n:empty\r\nno colon here\r\n\r\n"),
+	[]byte("Content-Length: 18446744073709551616\r\nCONTENT-length: 1\r\n\r\n"),
+}
+
+// checkParseHeaderLine compares ParseHeaderLine with the reference parser.
+// The only intended difference is a name that is not a token, which ParseHeaderLine rejects.
+func checkParseHeaderLine(t testing.TB, line []byte) {
+	t.Helper()
+	name, value := ParseHeaderLine(line)
+	wantName, wantValue := parseHeaderLineReference(line)
+	if wantValue != nil && (len(wantName) == 0 || indexNonTokenReference(wantName) != len(wantName)) {
+		wantName, wantValue = line[:0], nil
+	}
+	if !bytes.Equal(name, wantName) || !bytes.Equal(value, wantValue) || (value == nil) != (wantValue == nil) {
+		t.Fatalf("ParseHeaderLine(%q) = %q, %q, want %q, %q", line, name, value, wantName, wantValue)
+	}
+}
+
+// mutate returns a randomly mutated copy of src, biased towards bytes the scanners care about.
+func mutate(rng *rand.Rand, src []byte) []byte {
+	const interesting = "\r\n:\t ()\"/,;=?@[]{}\x00\x7f\x80\xff&%+"
+	b := append([]byte(nil), src...)
+	for n := rng.Intn(8); n >= 0; n-- {
+		if len(b) == 0 {
+			b = append(b, interesting[rng.Intn(len(interesting))])
+			continue
+		}
+		i := rng.Intn(len(b))
+		switch rng.Intn(4) {
+		case 0:
+			b[i] = interesting[rng.Intn(len(interesting))]
+		case 1:
+			b[i] = byte(rng.Intn(256))
+		case 2:
+			b = append(b[:i], b[i+1:]...)
+		case 3:
+			b = append(b[:i], append([]byte{interesting[rng.Intn(len(interesting))]}, b[i:]...)...)
+		}
+	}
+	return b
+}
+
+func Test_SWAR_Differential(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for iter := 0; iter < 20000; iter++ {
+		data := mutate(rng, swarSeeds[rng.Intn(len(swarSeeds))])
+
+		// Scanning primitives on every suffix shape
+		for _, c := range []byte{'\n', ':', ' ', '&', '='} {
+			if got, want := swarIndexByte(data, c), bytes.IndexByte(data, c); got != want {
+				t.Fatalf("swarIndexByte(%q, %q) = %d, want %d", data, c, got, want)
+			}
+		}
+		if got, want := indexNonToken(data), indexNonTokenReference(data); got != want {
+			t.Fatalf("indexNonToken(%q) = %d, want %d", data, got, want)
+		}
+		for i := 0; i <= len(data); i++ {
+			for _, j := range []int{i, len(data)} {
+				if got, want := skipLWS(data, i, j), skipLWSReference(data, i, j); got != want {
+					t.Fatalf("skipLWS(%q, %d, %d) = %d, want %d", data, i, j, got, want)
+				}
+				if got, want := skipLWSReverse(data, i, j-1), skipLWSReverseReference(data, i, j-1); got != want {
+					t.Fatalf("skipLWSReverse(%q, %d, %d) = %d, want %d", data, i, j-1, got, want)
+				}
+			}
+		}
+
+		// splitLine
+		line, rest, err := splitLine(data)
+		wantLine, wantRest, wantErr := splitLineReference(data)
+		if !bytes.Equal(line, wantLine) || !bytes.Equal(rest, wantRest) || err != wantErr {
+			t.Fatalf("splitLine(%q) mismatch", data)
+		}
+
+		// ParseHeaderLine on every line
+		next := data
+		for {
+			line, next, err = splitLine(next)
+			if err != nil {
+				break
+			}
+			checkParseHeaderLine(t, line)
+		}
+
+		// stricmp against a case-mutated copy
+		other := append([]byte(nil), data...)
+		for i := range other {
+			if rng.Intn(4) == 0 {
+				other[i] ^= 0x20
+			}
+		}
+		if rng.Intn(8) == 0 && len(other) > 0 {
+			other[rng.Intn(len(other))] ^= byte(rng.Intn(256))
+		}
+		if got, want := stricmp(data, other), stricmpReference(data, other); got != want {
+			t.Fatalf("stricmp(%q, %q) = %v, want %v", data, other, got, want)
+		}
+	}
+}
+
+func Test_ParseHeaderLine_InvalidName(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"Empty Name", ":value"},
+		{"Space In Name", "Bad Header: value"},
+		{"Space Before Colon", "Host : localhost"},
+		{"Separator", "x(y): z"},
+		{"DEL", "a\x7fb: c"},
+		{"Non-ASCII", "\xc3\xa9t\xc3\xa9: summer"},
+		{"No Colon", "no colon here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, value := ParseHeaderLine([]byte(tt.line))
+			if len(name) != 0 || value != nil {
+				t.Errorf("ParseHeaderLine(%q) = %q, %q, want an empty name and a nil value", tt.line, name, value)
+			}
+
+			var r Request
+			_, err := ParseHeaders(&r, []byte("Host: localhost\r\n"+tt.line+"\r\n\r\n"))
+			if err != ErrInvalidHeader {
+				t.Errorf("ParseHeaders() error = %v, want %v", err, ErrInvalidHeader)
+			}
+		})
+	}
+}
+
+func Test_SWAR_Masks(t *testing.T) {
+	var word [8]byte
+	for c := 0; c < 256; c++ {
+		for pos := 0; pos < 8; pos++ {
+			for i := range word {
+				word[i] = 'a'
+			}
+			word[pos] = byte(c)
+			x := swarLoad(word[:])
+
+			wantNonToken := -1
+			if !tokenTable[c] {
+				wantNonToken = pos
+			}
+			gotNonToken := -1
+			if mask := swarNonToken(x); mask != 0 {
+				gotNonToken = swarFirst(mask)
+			}
+			if gotNonToken != wantNonToken {
+				t.Fatalf("swarNonToken(%#x at %d) = %d, want %d", c, pos, gotNonToken, wantNonToken)
+			}
+
+			if got, want := swarEqual(x, byte(c)) != 0, true; got != want {
+				t.Fatalf("swarEqual(%#x at %d) = %v", c, pos, got)
+			}
+		}
+	}
+}
+
+func Benchmark_ParseHeaderLine(b *testing.B) {
+	line := []byte("User-Agent: Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/97.0.4692.99 Safari/537.36")
+	b.Run("SWAR", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ParseHeaderLine(line)
+		}
+	})
+	b.Run("Reference", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			parseHeaderLineReference(line)
+		}
+	})
+}
@@ -17,6 +17,7 @@ var Benchmarks = []string{
 	"Benchmark_H1_URI_Parse",
 	"Benchmark_H1_URI_Query",
 	"Benchmark_Request_Reader",
+	"Benchmark_ParseHeaderLine",
 }
 
 func main() {
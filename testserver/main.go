@@ -1,71 +1,30 @@
 package main
 
 import (
-	"io"
 	"log"
-	"net"
+	"time"
 
 	"github.com/go-www/h1"
 )
 
-type LogWriter struct {
-	io.Writer
-}
-
-func (w *LogWriter) Write(p []byte) (n int, err error) {
-	log.Println(string(p))
-	return w.Writer.Write(p)
-}
-
-func handleConnection(conn net.Conn) {
-	defer conn.Close()
-	reader := h1.RequestReader{
-		R:          conn,
-		ReadBuffer: make([]byte, 8192),
-		NextBuffer: nil,
-		Request:    h1.Request{},
-	}
-	resp := h1.GetResponse(conn)
-	defer h1.PutResponse(resp)
-	for {
-		_, err := reader.Next()
-		if err != nil {
-			if err == io.EOF {
-				log.Println("EOF")
-				return
-			}
-			log.Println(err)
-			return
-		}
-
-		resp.ContentLength = 13
-		//resp.Connection = h1.ConnectionKeepAlive
-		resp.WriteHeader(200)
-		resp.WriteString("Hello, World!")
-
-		if reader.Remaining() == 0 {
-			err = resp.Flush()
-			if err != nil {
-				log.Println(err)
-				return
-			}
-		}
-	}
+func handler(resp *h1.Response, req *h1.RequestReader) error {
+	resp.ContentLength = 13
+	//resp.Connection = h1.ConnectionKeepAlive
+	resp.WriteHeader(200)
+	resp.EndHeader()
+	_, err := resp.WriteString("Hello, World!")
+	return err
 }
 
 func main() {
-	ln, err := net.Listen("tcp", ":50901")
-	if err != nil {
-		log.Fatal(err)
+	s := &h1.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
 	}
-	defer ln.Close()
-	log.Println("Listening on http://localhost:50901")
 
-	for {
-		c, err := ln.Accept()
-		if err != nil {
-			log.Println(err)
-		}
-		go handleConnection(c)
-	}
+	log.Println("Listening on http://localhost:50901")
+	log.Fatal(s.ListenAndServe(":50901"))
 }
@@ -3,6 +3,8 @@ package h1
 import (
 	"bytes"
 	"errors"
+	"strconv"
+	"time"
 
 	"github.com/go-www/h1/encoding/percent"
 )
@@ -18,8 +20,15 @@ type URI struct {
 	RawPath  []byte
 	RawQuery []byte
 
+	// QueryOptions is used when parsing RawQuery. It is not cleared by Reset.
+	QueryOptions QueryOptions
+
 	isQueryParsed bool
 	queryArgs     []Query
+
+	isPathDecoded bool
+	path          []byte
+	pathBuffer    []byte
 }
 
 func (u *URI) Reset() {
@@ -28,68 +37,194 @@ func (u *URI) Reset() {
 	u.RawQuery = nil
 	u.isQueryParsed = false
 	u.queryArgs = u.queryArgs[:0]
+	u.isPathDecoded = false
+	u.path = nil
+}
+
+// URI character classes from RFC 3986 Section 2 and 3.
+const (
+	uriClassPath  uint8 = 1 << iota // pchar / "/"
+	uriClassQuery                   // pchar / "/" / "?" (also used for fragments)
+	uriClassHex                     // HEXDIG
+)
+
+var uriCharTable [256]uint8
+
+var _ = func() int {
+	const unreserved = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-._~"
+	const subDelims = "!$&'()*+,;="
+
+	for _, c := range []byte(unreserved + subDelims + ":@/") {
+		uriCharTable[c] |= uriClassPath | uriClassQuery
+	}
+	uriCharTable['?'] |= uriClassQuery
+
+	for _, c := range []byte("0123456789abcdefABCDEF") {
+		uriCharTable[c] |= uriClassHex
+	}
+
+	return 0
+}()
+
+// validURIComponent reports whether every byte of src belongs to class,
+// allowing percent-encoded octets ("%" HEXDIG HEXDIG).
+func validURIComponent(src []byte, class uint8) bool {
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if uriCharTable[c]&class != 0 {
+			continue
+		}
+		if c != '%' || i+2 >= len(src) ||
+			uriCharTable[src[i+1]]&uriClassHex == 0 ||
+			uriCharTable[src[i+2]]&uriClassHex == 0 {
+			return false
+		}
+		i += 2
+	}
+	return true
 }
 
-func (u *URI) Parse(uri []byte) {
+// Parse splits uri into its path and query components.
+// The fragment (if any) is stripped, since clients must not send it.
+// ErrInvalidURI is returned if a component contains characters
+// that are not allowed in a request-target.
+func (u *URI) Parse(uri []byte) error {
 	u.Reset()
 
 	u.RawURI = uri
 
-	// Parse the URI
-	// Find the ?
+	if len(uri) == 0 {
+		return ErrInvalidURI
+	}
+
+	// Strip the fragment
+	if FIndex := indexByte(uri, '#'); FIndex != -1 {
+		if !validURIComponent(uri[FIndex+1:], uriClassQuery) {
+			return ErrInvalidURI
+		}
+		uri = uri[:FIndex]
+	}
 
-	QIndex := bytes.IndexByte(uri, '?')
+	// Find the ?
+	QIndex := indexByte(uri, '?')
 	u.RawPath = uri
 	if QIndex != -1 {
 		u.RawPath = uri[:QIndex]
 		u.RawQuery = uri[QIndex+1:]
 	}
+
+	if !validURIComponent(u.RawPath, uriClassPath) ||
+		!validURIComponent(u.RawQuery, uriClassQuery) {
+		u.RawPath = nil
+		u.RawQuery = nil
+		return ErrInvalidURI
+	}
+
+	return nil
 }
 
+// Path returns the percent-decoded path.
+// It is RawPath itself unless RawPath contains escapes, which are decoded into a buffer owned by u.
 func (u *URI) Path() []byte {
-	return u.RawPath
+	if !u.isPathDecoded {
+		u.isPathDecoded = true
+		u.path = u.RawPath
+		if indexByte(u.RawPath, '%') != -1 {
+			decoded, err := percent.AppendDecode(u.pathBuffer[:0], u.RawPath)
+			if err == nil {
+				u.pathBuffer = decoded
+				u.path = decoded
+			}
+		}
+	}
+	return u.path
 }
 
 func (u *URI) parseQuery() {
-	u.queryArgs = ParseRawQuery(u.RawQuery, u.queryArgs)
+	u.queryArgs, _ = ParseRawQueryOptions(u.RawQuery, u.queryArgs, u.QueryOptions)
+}
+
+// DefaultMaxQueryArgs is the number of arguments parsed when QueryOptions.MaxArgs is zero.
+const DefaultMaxQueryArgs = 1024
+
+var ErrTooManyQueryArgs = errors.New("too many query arguments")
+
+// QueryOptions controls how application/x-www-form-urlencoded data is parsed.
+// The zero value follows the WHATWG URL Standard.
+type QueryOptions struct {
+	// KeepPlus disables decoding '+' as a space.
+	KeepPlus bool
+
+	// MaxArgs limits the number of parsed arguments.
+	// Zero means DefaultMaxQueryArgs, a negative value means no limit.
+	MaxArgs int
 }
 
+// ParseRawQuery parses rawQuery with the default QueryOptions and appends the arguments to dst.
+// Arguments beyond DefaultMaxQueryArgs are ignored.
 func ParseRawQuery(rawQuery []byte, dst []Query) []Query {
+	dst, _ = ParseRawQueryOptions(rawQuery, dst, QueryOptions{})
+	return dst
+}
+
+// ParseRawQueryOptions parses rawQuery as application/x-www-form-urlencoded data
+// and appends the arguments to dst.
+// Keys and values are decoded in place, so rawQuery is modified.
+// If the argument limit is reached, the arguments parsed so far are returned with ErrTooManyQueryArgs.
+func ParseRawQueryOptions(rawQuery []byte, dst []Query, opts QueryOptions) ([]Query, error) {
+	maxArgs := opts.MaxArgs
+	if maxArgs == 0 {
+		maxArgs = DefaultMaxQueryArgs
+	}
+
 	next := rawQuery
+	var count int
 
-	for {
-		var key, value []byte
+	for len(next) > 0 {
+		var pair []byte
 
-		// Find the Key
-		keyEnd := bytes.IndexByte(next, '=')
-		if keyEnd == -1 {
-			break
+		// Find the end of the pair
+		ampIndex := indexByte(next, '&')
+		if ampIndex != -1 {
+			pair = next[:ampIndex]
+			next = next[ampIndex+1:]
+		} else {
+			pair = next
+			next = nil
 		}
 
-		key = next[:keyEnd]
-		next = next[keyEnd+1:]
+		// Skip empty pairs ("a=1&&b=2")
+		if len(pair) == 0 {
+			continue
+		}
 
-		// Find the Value
+		if maxArgs > 0 && count >= maxArgs {
+			return dst, ErrTooManyQueryArgs
+		}
 
-		valueEnd := bytes.IndexByte(next, '&')
-		if valueEnd > 0 {
-			value = next[:valueEnd]
-			next = next[valueEnd+1:]
-		} else {
-			dst = append(dst, Query{
-				Key:   key,
-				Value: percent.Decode(next),
-			})
-			break
+		// Split the Key and the Value, a pair without '=' has an empty value
+		key, value := pair, pair[len(pair):]
+		eqIndex := indexByte(pair, '=')
+		if eqIndex != -1 {
+			key = pair[:eqIndex]
+			value = pair[eqIndex+1:]
 		}
 
 		dst = append(dst, Query{
-			Key:   key,
-			Value: percent.Decode(value),
+			Key:   decodeQueryComponent(key, !opts.KeepPlus),
+			Value: decodeQueryComponent(value, !opts.KeepPlus),
 		})
+		count++
 	}
 
-	return dst
+	return dst, nil
+}
+
+func decodeQueryComponent(src []byte, plusAsSpace bool) []byte {
+	if plusAsSpace {
+		return percent.DecodeForm(src)
+	}
+	return percent.Decode(src)
 }
 
 func (u *URI) Query() []Query {
@@ -112,3 +247,110 @@ func (u *URI) QueryValue(key []byte) ([]byte, error) {
 
 	return nil, ErrKeyNotFound
 }
+
+// HasQuery reports whether key is present in the query, with or without a value.
+func (u *URI) HasQuery(key []byte) bool {
+	_, err := u.QueryValue(key)
+	return err == nil
+}
+
+// QueryIterator iterates over the values of a query key.
+type QueryIterator struct {
+	args  []Query
+	key   []byte
+	index int
+}
+
+// Next returns the next value of the key. ok is false when there are no more values.
+func (it *QueryIterator) Next() (value []byte, ok bool) {
+	for it.index < len(it.args) {
+		q := &it.args[it.index]
+		it.index++
+		if bytes.Equal(q.Key, it.key) {
+			return q.Value, true
+		}
+	}
+	return nil, false
+}
+
+// QueryValues returns an iterator over all values of key, in the order they appear in the query.
+func (u *URI) QueryValues(key []byte) QueryIterator {
+	return QueryIterator{
+		args: u.Query(),
+		key:  key,
+	}
+}
+
+var ErrInvalidQueryValue = errors.New("invalid query value")
+
+// Note: The typed getters below parse the decoded value without copying it.
+// strconv errors are not returned since they would retain a reference to the request buffer.
+
+// QueryInt returns the first value of key parsed as a base 10 int.
+func (u *URI) QueryInt(key []byte) (int, error) {
+	v, err := u.QueryValue(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(bytesToString(v), 10, 0)
+	if err != nil {
+		return 0, ErrInvalidQueryValue
+	}
+	return int(i), nil
+}
+
+// QueryInt64 returns the first value of key parsed as a base 10 int64.
+func (u *URI) QueryInt64(key []byte) (int64, error) {
+	v, err := u.QueryValue(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(bytesToString(v), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidQueryValue
+	}
+	return i, nil
+}
+
+// QueryBool returns the first value of key parsed with strconv.ParseBool.
+// A key without a value ("?debug") is true.
+func (u *URI) QueryBool(key []byte) (bool, error) {
+	v, err := u.QueryValue(key)
+	if err != nil {
+		return false, err
+	}
+	if len(v) == 0 {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(bytesToString(v))
+	if err != nil {
+		return false, ErrInvalidQueryValue
+	}
+	return b, nil
+}
+
+// QueryFloat returns the first value of key parsed as a float64.
+func (u *URI) QueryFloat(key []byte) (float64, error) {
+	v, err := u.QueryValue(key)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(bytesToString(v), 64)
+	if err != nil {
+		return 0, ErrInvalidQueryValue
+	}
+	return f, nil
+}
+
+// QueryDuration returns the first value of key parsed with time.ParseDuration.
+func (u *URI) QueryDuration(key []byte) (time.Duration, error) {
+	v, err := u.QueryValue(key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(bytesToString(v))
+	if err != nil {
+		return 0, ErrInvalidQueryValue
+	}
+	return d, nil
+}
@@ -3,7 +3,10 @@ package h1
 import (
 	"fmt"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Benchmark_Net_URL_Parse(b *testing.B) {
@@ -62,3 +65,184 @@ func Test_H1_URI_Parse(t *testing.T) {
 		})
 	}
 }
+
+func Test_URI_Parse_Validation(t *testing.T) {
+	tests := []struct {
+		name      string
+		uri       string
+		wantPath  string
+		wantQuery string
+		wantErr   bool
+	}{
+		{"root", "/", "/", "", false},
+		{"path and query", "/some/path?foo=bar&baz=qux", "/some/path", "foo=bar&baz=qux", false},
+		{"asterisk", "*", "*", "", false},
+		{"absolute form", "http://example.com:8080/a/b?c=d", "http://example.com:8080/a/b", "c=d", false},
+		{"question mark in query", "/a?b=c?d", "/a", "b=c?d", false},
+		{"percent encoded", "/a%20b?c=%F0%9F%8E%89", "/a%20b", "c=%F0%9F%8E%89", false},
+		{"fragment", "/a/b#frag", "/a/b", "", false},
+		{"fragment after query", "/a?b=c#frag?x", "/a", "b=c", false},
+		{"empty", "", "", "", true},
+		{"space in path", "/a b", "", "", true},
+		{"space in query", "/a?b=c d", "", "", true},
+		{"control character", "/a\x01", "", "", true},
+		{"non-ascii", "/caf\xc3\xa9", "", "", true},
+		{"invalid escape", "/a%zz", "", "", true},
+		{"incomplete escape", "/a%4", "", "", true},
+		{"question mark in path", "/a?b?c", "/a", "b?c", false},
+		{"invalid fragment", "/a#b c", "", "", true},
+		{"second fragment", "/a#b#c", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri := URI{}
+			err := uri.Parse([]byte(tt.uri))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if err != ErrInvalidURI {
+					t.Errorf("Parse() error = %v, want %v", err, ErrInvalidURI)
+				}
+				return
+			}
+			if string(uri.RawPath) != tt.wantPath {
+				t.Errorf("RawPath = %q, want %q", uri.RawPath, tt.wantPath)
+			}
+			if string(uri.RawQuery) != tt.wantQuery {
+				t.Errorf("RawQuery = %q, want %q", uri.RawQuery, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func Test_URI_Path(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"/", "/"},
+		{"/a/b?c=%20", "/a/b"},
+		{"/a%20b", "/a b"},
+		{"/caf%C3%A9/%2F", "/caf\xc3\xa9//"},
+		{"/a+b", "/a+b"},
+	}
+
+	var uri URI
+	for _, tt := range tests {
+		if err := uri.Parse([]byte(tt.uri)); err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.uri, err)
+		}
+		if got := uri.Path(); string(got) != tt.want {
+			t.Errorf("Path() of %q = %q, want %q", tt.uri, got, tt.want)
+		}
+		if string(uri.RawPath) != strings.SplitN(tt.uri, "?", 2)[0] {
+			t.Errorf("RawPath of %q = %q after Path()", tt.uri, uri.RawPath)
+		}
+	}
+}
+
+func Test_ParseRawQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		opts QueryOptions
+		want [][2]string
+		err  error
+	}{
+		{"empty", "", QueryOptions{}, nil, nil},
+		{"single", "a=1", QueryOptions{}, [][2]string{{"a", "1"}}, nil},
+		{"valueless key", "debug&x=1", QueryOptions{}, [][2]string{{"debug", ""}, {"x", "1"}}, nil},
+		{"valueless last key", "x=1&debug", QueryOptions{}, [][2]string{{"x", "1"}, {"debug", ""}}, nil},
+		{"empty value", "a=&b=2", QueryOptions{}, [][2]string{{"a", ""}, {"b", "2"}}, nil},
+		{"empty pairs", "&a=1&&b=2&", QueryOptions{}, [][2]string{{"a", "1"}, {"b", "2"}}, nil},
+		{"empty key", "=1", QueryOptions{}, [][2]string{{"", "1"}}, nil},
+		{"equals in value", "a=b=c", QueryOptions{}, [][2]string{{"a", "b=c"}}, nil},
+		{"key decoding", "a%20b=c%26d", QueryOptions{}, [][2]string{{"a b", "c&d"}}, nil},
+		{"plus as space", "q=hello+world&a+b=1", QueryOptions{}, [][2]string{{"q", "hello world"}, {"a b", "1"}}, nil},
+		{"keep plus", "q=hello+world", QueryOptions{KeepPlus: true}, [][2]string{{"q", "hello+world"}}, nil},
+		{"encoded plus", "q=1%2B1", QueryOptions{}, [][2]string{{"q", "1+1"}}, nil},
+		{"max args", "a=1&b=2&c=3", QueryOptions{MaxArgs: 2}, [][2]string{{"a", "1"}, {"b", "2"}}, ErrTooManyQueryArgs},
+		{"unlimited args", "a=1&b=2&c=3", QueryOptions{MaxArgs: -1}, [][2]string{{"a", "1"}, {"b", "2"}, {"c", "3"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRawQueryOptions([]byte(tt.raw), nil, tt.opts)
+			if err != tt.err {
+				t.Fatalf("ParseRawQueryOptions() error = %v, want %v", err, tt.err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseRawQueryOptions() got %d args, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if string(got[i].Key) != tt.want[i][0] || string(got[i].Value) != tt.want[i][1] {
+					t.Errorf("arg %d = %q=%q, want %q=%q", i, got[i].Key, got[i].Value, tt.want[i][0], tt.want[i][1])
+				}
+			}
+		})
+	}
+}
+
+func Test_URI_TypedQuery(t *testing.T) {
+	uri := URI{}
+	err := uri.Parse([]byte("/api?id=42&big=9007199254740993&on=true&debug&ratio=0.5&wait=1m30s&tag=a&tag=b&tag=c&bad=x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := uri.QueryInt([]byte("id")); err != nil || v != 42 {
+		t.Errorf("QueryInt() = %v, %v", v, err)
+	}
+	if v, err := uri.QueryInt64([]byte("big")); err != nil || v != 9007199254740993 {
+		t.Errorf("QueryInt64() = %v, %v", v, err)
+	}
+	if v, err := uri.QueryBool([]byte("on")); err != nil || !v {
+		t.Errorf("QueryBool(on) = %v, %v", v, err)
+	}
+	if v, err := uri.QueryBool([]byte("debug")); err != nil || !v {
+		t.Errorf("QueryBool(debug) = %v, %v", v, err)
+	}
+	if v, err := uri.QueryFloat([]byte("ratio")); err != nil || v != 0.5 {
+		t.Errorf("QueryFloat() = %v, %v", v, err)
+	}
+	if v, err := uri.QueryDuration([]byte("wait")); err != nil || v != 90*time.Second {
+		t.Errorf("QueryDuration() = %v, %v", v, err)
+	}
+	if _, err := uri.QueryInt([]byte("bad")); err != ErrInvalidQueryValue {
+		t.Errorf("QueryInt(bad) error = %v, want %v", err, ErrInvalidQueryValue)
+	}
+	if _, err := uri.QueryInt([]byte("missing")); err != ErrKeyNotFound {
+		t.Errorf("QueryInt(missing) error = %v, want %v", err, ErrKeyNotFound)
+	}
+
+	if !uri.HasQuery([]byte("debug")) {
+		t.Error("HasQuery(debug) = false, want true")
+	}
+	if uri.HasQuery([]byte("missing")) {
+		t.Error("HasQuery(missing) = true, want false")
+	}
+
+	var tags []string
+	it := uri.QueryValues([]byte("tag"))
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		tags = append(tags, string(v))
+	}
+	if !reflect.DeepEqual(tags, []string{"a", "b", "c"}) {
+		t.Errorf("QueryValues() = %v, want [a b c]", tags)
+	}
+}
+
+func Benchmark_H1_URI_QueryInt(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(p *testing.PB) {
+		uri := URI{}
+		data := []byte("/api?limit=100&offset=20")
+		key := []byte("offset")
+		for p.Next() {
+			uri.Parse(data)
+			uri.QueryInt(key)
+		}
+	})
+}